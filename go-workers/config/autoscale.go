@@ -0,0 +1,64 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// AutoscaleConfig drives the autoscaler: every Interval each queue gets one worker per MessagesPerWorker
+// ready or in-flight messages, kept within its Bounds. While enabled it overrides manual and reloaded
+// worker counts.
+type AutoscaleConfig struct {
+	Enabled           bool
+	Interval          time.Duration
+	MessagesPerWorker int
+	Bounds            map[string]WorkerBounds
+}
+
+// WorkerBounds are the minimum and maximum worker count of a queue
+type WorkerBounds struct {
+	Min int
+	Max int
+}
+
+// loadAutoscaleConfig reads AUTOSCALE_* and the per queue <QUEUE>_MIN_WORKERS / <QUEUE>_MAX_WORKERS bounds,
+// which default to AUTOSCALE_MIN_WORKERS and AUTOSCALE_MAX_WORKERS
+func loadAutoscaleConfig(queueNames []string) (AutoscaleConfig, error) {
+	autoscale := AutoscaleConfig{Bounds: make(map[string]WorkerBounds, len(queueNames))}
+	var err error
+	if autoscale.Enabled, err = getEnvBool("AUTOSCALE_ENABLED", false); err != nil {
+		return autoscale, err
+	}
+	if autoscale.Interval, err = getEnvDuration("AUTOSCALE_INTERVAL", 15*time.Second); err != nil {
+		return autoscale, err
+	}
+	if autoscale.MessagesPerWorker, err = getEnvInt("AUTOSCALE_MESSAGES_PER_WORKER", 10); err != nil {
+		return autoscale, err
+	}
+	if autoscale.MessagesPerWorker < 1 {
+		return autoscale, fmt.Errorf("AUTOSCALE_MESSAGES_PER_WORKER must be at least 1")
+	}
+	defaultMin, err := getEnvInt("AUTOSCALE_MIN_WORKERS", 1)
+	if err != nil {
+		return autoscale, err
+	}
+	defaultMax, err := getEnvInt("AUTOSCALE_MAX_WORKERS", 5)
+	if err != nil {
+		return autoscale, err
+	}
+
+	for _, queueName := range queueNames {
+		var bounds WorkerBounds
+		if bounds.Min, err = getEnvInt(queueEnvName(queueName, "MIN_WORKERS"), defaultMin); err != nil {
+			return autoscale, err
+		}
+		if bounds.Max, err = getEnvInt(queueEnvName(queueName, "MAX_WORKERS"), defaultMax); err != nil {
+			return autoscale, err
+		}
+		if bounds.Min < 0 || bounds.Max < bounds.Min {
+			return autoscale, fmt.Errorf("invalid worker bounds for %s: min %d, max %d", queueName, bounds.Min, bounds.Max)
+		}
+		autoscale.Bounds[queueName] = bounds
+	}
+	return autoscale, nil
+}
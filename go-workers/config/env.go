@@ -0,0 +1,86 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// getEnvBool reads a boolean env var, falling back to def when it is not set
+func getEnvBool(name string, def bool) (bool, error) {
+	raw := os.Getenv(name)
+	if raw == "" {
+		return def, nil
+	}
+	value, err := strconv.ParseBool(raw)
+	if err != nil {
+		return def, fmt.Errorf("invalid %s value %q: %w", name, raw, err)
+	}
+	return value, nil
+}
+
+// getEnvInt reads an integer env var, falling back to def when it is not set
+func getEnvInt(name string, def int) (int, error) {
+	raw := os.Getenv(name)
+	if raw == "" {
+		return def, nil
+	}
+	value, err := strconv.Atoi(raw)
+	if err != nil {
+		return def, fmt.Errorf("invalid %s value %q: %w", name, raw, err)
+	}
+	return value, nil
+}
+
+// getEnvFloat reads a float env var, falling back to def when it is not set
+func getEnvFloat(name string, def float64) (float64, error) {
+	raw := os.Getenv(name)
+	if raw == "" {
+		return def, nil
+	}
+	value, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		return def, fmt.Errorf("invalid %s value %q: %w", name, raw, err)
+	}
+	return value, nil
+}
+
+// getEnvDurations reads a comma separated list of durations (e.g. "10s,1m"), falling back to def when it is not set
+func getEnvDurations(name string, def []time.Duration) ([]time.Duration, error) {
+	raw := os.Getenv(name)
+	if raw == "" {
+		return def, nil
+	}
+	var values []time.Duration
+	for _, part := range strings.Split(raw, ",") {
+		value, err := time.ParseDuration(strings.TrimSpace(part))
+		if err != nil {
+			return def, fmt.Errorf("invalid %s value %q: %w", name, raw, err)
+		}
+		if value <= 0 {
+			return def, fmt.Errorf("invalid %s value %q: durations must be positive", name, raw)
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+// getEnvDuration reads a duration env var (e.g. "30s"), falling back to def when it is not set
+func getEnvDuration(name string, def time.Duration) (time.Duration, error) {
+	raw := os.Getenv(name)
+	if raw == "" {
+		return def, nil
+	}
+	value, err := time.ParseDuration(raw)
+	if err != nil {
+		return def, fmt.Errorf("invalid %s value %q: %w", name, raw, err)
+	}
+	return value, nil
+}
+
+// queueEnvName builds the name of a per queue override, e.g. convert_queue + PREFETCH -> CONVERT_QUEUE_PREFETCH
+func queueEnvName(queueName string, suffix string) string {
+	return strings.ToUpper(strings.NewReplacer("-", "_", ".", "_").Replace(queueName)) + "_" + suffix
+}
@@ -4,7 +4,14 @@ import (
 	"fmt"
 	"log"
 	"os"
-	"strings"
+	"time"
+
+	"github.com/mahirjain10/go-workers/internal/admission"
+	"github.com/mahirjain10/go-workers/internal/idempotency"
+	"github.com/mahirjain10/go-workers/internal/message"
+	"github.com/mahirjain10/go-workers/internal/retry"
+	"github.com/mahirjain10/go-workers/internal/types"
+	"github.com/mahirjain10/go-workers/internal/validation"
 
 	godotenv "github.com/joho/godotenv"
 )
@@ -12,8 +19,81 @@ import (
 type Config struct {
 	RabbitMqURL    string
 	RabbitMqQueues []string
-	AwsBucketName  string
-	DbURL          string
+	// Routing selects per type queues or the topic exchange mode and names the status queue
+	Routing       RoutingConfig
+	AwsBucketName string
+	DbURL         string
+	// EnablePlaceholders turns on BlurHash and LQIP generation for processed images
+	EnablePlaceholders bool
+	// InspectDefaults are the thresholds used by INSPECT jobs that don't send their own
+	InspectDefaults types.Inspect
+	// ImageLimits protect the worker from decompression bombs
+	ImageLimits validation.Limits
+	// MemoryBudget caps the estimated decoded bytes of all in-flight jobs, 0 disables admission control
+	MemoryBudget int64
+	// RetryTiers are the delays of the retry queues, a retryable failure moves one tier further per attempt
+	RetryTiers []time.Duration
+	// S3RetryPolicy is the retry policy of S3 operations, S3RetryPolicies overrides it per queue
+	S3RetryPolicy   retry.Policy
+	S3RetryPolicies map[string]retry.Policy
+	// Idempotency selects where completed jobs are remembered to skip redeliveries
+	Idempotency idempotency.Options
+	// OutboxPath is the file of unconfirmed status messages, retried every OutboxRetryInterval
+	OutboxPath          string
+	OutboxRetryInterval time.Duration
+	// ShutdownTimeout is how long in-flight jobs get to finish after SIGTERM
+	ShutdownTimeout time.Duration
+	// Prefetch is how many unacked messages each consumer channel holds, Prefetches overrides it per queue
+	Prefetch   int
+	Prefetches map[string]int
+	// WorkerConfigFile optionally holds per queue worker counts, see LoadWorkerCounts
+	WorkerConfigFile string
+	// AdminAddr is the listen address of the admin HTTP endpoint, empty disables it
+	AdminAddr string
+	// Autoscale sizes the worker pools from the queue depth when enabled
+	Autoscale AutoscaleConfig
+	// QueueMaxPriority is the x-max-priority of the work queues, 0 declares them as plain FIFO queues
+	QueueMaxPriority uint8
+	// UserLimits keep one user from starving the others, jobs over them wait UserDeferDelay in a TTL queue
+	UserLimits     admission.UserLimits
+	UserDeferDelay time.Duration
+	// CancelTTL is how long a cancel is remembered to drop the job when it is consumed later
+	CancelTTL time.Duration
+	// JobTimeout bounds a whole job, JobTimeouts overrides it per transformation type
+	JobTimeout  time.Duration
+	JobTimeouts map[string]time.Duration
+	// MaxAbandoned pauses the consumers while this many timed out or cancelled transformations still run
+	// in the background, 0 disables the check
+	MaxAbandoned int
+	// MaxJobAge rejects jobs created longer ago than this as EXPIRED, 0 disables the check
+	MaxJobAge time.Duration
+	// StatusContentType encodes the published statuses, application/json or application/x-protobuf. Jobs
+	// are decoded by the content type of each delivery.
+	StatusContentType string
+}
+
+// RetryPolicyFor returns the S3 retry policy of the given queue
+func (c *Config) RetryPolicyFor(queueName string) retry.Policy {
+	if policy, ok := c.S3RetryPolicies[queueName]; ok {
+		return policy
+	}
+	return c.S3RetryPolicy
+}
+
+// TimeoutFor returns the deadline of a job of the given transformation type
+func (c *Config) TimeoutFor(transformationType string) time.Duration {
+	if timeout, ok := c.JobTimeouts[transformationType]; ok {
+		return timeout
+	}
+	return c.JobTimeout
+}
+
+// PrefetchFor returns the prefetch count of the given queue's consumer channels
+func (c *Config) PrefetchFor(queueName string) int {
+	if prefetch, ok := c.Prefetches[queueName]; ok {
+		return prefetch
+	}
+	return c.Prefetch
 }
 
 func NewConfig(url string, queueNames []string, bucketName string, dbUrl string) *Config {
@@ -32,7 +112,7 @@ func InitializeEnvs() (*Config, error) {
 	}
 	log.Println("Working dir:", wd)
 
-	switch os.Getenv("APP_ENV") { 
+	switch os.Getenv("APP_ENV") {
 	case "docker":
 		if err := godotenv.Overload(".env.docker"); err == nil {
 			log.Println("Loaded .env.docker")
@@ -59,21 +139,208 @@ func InitializeEnvs() (*Config, error) {
 	}
 
 	url := os.Getenv("RABBITMQ_URL")
-	queues := os.Getenv("RABBITMQ_QUEUES")
 	aws_region := os.Getenv("AWS_REGION")
 	aws_access_key_id := os.Getenv("AWS_ACCESS_KEY_ID")
 	aws_secret_access_key := os.Getenv("AWS_SECRET_ACCESS_KEY")
 	aws_bucket_name := os.Getenv("AWS_BUCKET_NAME")
 	db_url := os.Getenv("DATABASE_URL")
 
-	fmt.Println("Printing", url, queues, aws_region, aws_access_key_id, aws_secret_access_key)
-	if url == "" || queues == "" || aws_region == "" || aws_access_key_id == "" || aws_secret_access_key == "" || aws_bucket_name == "" || db_url == "" {
-		return nil, fmt.Errorf("RABBITMQ_URL or RABBITMQ_QUEUES or AWS_REGION or AWS_ACCESS_KEY_ID or AWS_SECRET_ACCESS_KEY or AWS_BUCKET_NAME or DATABASE_URL is missing")
+	if url == "" || aws_region == "" || aws_access_key_id == "" || aws_secret_access_key == "" || aws_bucket_name == "" || db_url == "" {
+		return nil, fmt.Errorf("RABBITMQ_URL or AWS_REGION or AWS_ACCESS_KEY_ID or AWS_SECRET_ACCESS_KEY or AWS_BUCKET_NAME or DATABASE_URL is missing")
 	}
-	queuesArray := strings.Split(queues, ",")
-	for i := range queuesArray {
-		queuesArray[i] = strings.TrimSpace(queuesArray[i])
+	routing, err := loadRoutingConfig()
+	if err != nil {
+		return nil, err
+	}
+	queuesArray, err := loadQueues(routing)
+	if err != nil {
+		return nil, err
 	}
 	config := NewConfig(url, queuesArray, aws_bucket_name, db_url)
+	config.Routing = routing
+
+	if config.EnablePlaceholders, err = getEnvBool("ENABLE_PLACEHOLDERS", false); err != nil {
+		return nil, err
+	}
+	if config.InspectDefaults, err = loadInspectDefaults(); err != nil {
+		return nil, err
+	}
+	if config.ImageLimits, err = loadImageLimits(); err != nil {
+		return nil, err
+	}
+	memoryBudgetMb, err := getEnvInt("MEMORY_BUDGET_MB", 1024)
+	if err != nil {
+		return nil, err
+	}
+	config.MemoryBudget = int64(memoryBudgetMb) << 20
+	if config.MaxAbandoned, err = getEnvInt("MAX_ABANDONED_TRANSFORMATIONS", 4); err != nil {
+		return nil, err
+	}
+	if config.RetryTiers, err = getEnvDurations("RETRY_TIERS", []time.Duration{10 * time.Second, time.Minute, 10 * time.Minute}); err != nil {
+		return nil, err
+	}
+	if config.S3RetryPolicy, err = loadRetryPolicy("S3_RETRY", retry.DefaultPolicy); err != nil {
+		return nil, err
+	}
+	config.S3RetryPolicies = make(map[string]retry.Policy, len(config.WorkQueues()))
+	for _, queueName := range config.WorkQueues() {
+		if config.S3RetryPolicies[queueName], err = loadRetryPolicy(queueEnvName(queueName, "S3_RETRY"), config.S3RetryPolicy); err != nil {
+			return nil, err
+		}
+	}
+	if config.Idempotency, err = loadIdempotencyOptions(); err != nil {
+		return nil, err
+	}
+	if config.OutboxPath = os.Getenv("OUTBOX_PATH"); config.OutboxPath == "" {
+		config.OutboxPath = "outbox.db"
+	}
+	if config.OutboxRetryInterval, err = getEnvDuration("OUTBOX_RETRY_INTERVAL", 5*time.Second); err != nil {
+		return nil, err
+	}
+	if config.ShutdownTimeout, err = getEnvDuration("SHUTDOWN_TIMEOUT", 30*time.Second); err != nil {
+		return nil, err
+	}
+	if config.Prefetch, err = getEnvInt("PREFETCH", 1); err != nil {
+		return nil, err
+	}
+	config.WorkerConfigFile = os.Getenv("WORKER_CONFIG_FILE")
+	config.AdminAddr = os.Getenv("ADMIN_ADDR")
+	if _, err := config.LoadWorkerCounts(); err != nil {
+		return nil, err
+	}
+	maxPriority, err := getEnvInt("QUEUE_MAX_PRIORITY", 0)
+	if err != nil {
+		return nil, err
+	}
+	if maxPriority < 0 || maxPriority > 255 {
+		return nil, fmt.Errorf("QUEUE_MAX_PRIORITY must be between 0 and 255, got %d", maxPriority)
+	}
+	config.QueueMaxPriority = uint8(maxPriority)
+	if config.UserLimits, err = loadUserLimits(); err != nil {
+		return nil, err
+	}
+	if config.UserDeferDelay, err = getEnvDuration("USER_DEFER_DELAY", 5*time.Second); err != nil {
+		return nil, err
+	}
+	if config.CancelTTL, err = getEnvDuration("CANCEL_TTL", 24*time.Hour); err != nil {
+		return nil, err
+	}
+	if config.JobTimeout, err = getEnvDuration("JOB_TIMEOUT", 2*time.Minute); err != nil {
+		return nil, err
+	}
+	config.JobTimeouts = make(map[string]time.Duration, len(TransformationTypes))
+	for _, transformationType := range TransformationTypes {
+		if config.JobTimeouts[transformationType], err = getEnvDuration(transformationType+"_TIMEOUT", config.JobTimeout); err != nil {
+			return nil, err
+		}
+	}
+	if config.MaxJobAge, err = getEnvDuration("MAX_JOB_AGE", 0); err != nil {
+		return nil, err
+	}
+	config.StatusContentType = getEnvString("STATUS_CONTENT_TYPE", message.ContentTypeJSON)
+	if config.StatusContentType != message.ContentTypeJSON && config.StatusContentType != message.ContentTypeProtobuf {
+		return nil, fmt.Errorf("STATUS_CONTENT_TYPE must be %s or %s, got %q", message.ContentTypeJSON, message.ContentTypeProtobuf, config.StatusContentType)
+	}
+	if config.Autoscale, err = loadAutoscaleConfig(config.WorkQueues()); err != nil {
+		return nil, err
+	}
+	config.Prefetches = make(map[string]int, len(config.WorkQueues()))
+	for _, queueName := range config.WorkQueues() {
+		if config.Prefetches[queueName], err = getEnvInt(queueEnvName(queueName, "PREFETCH"), config.Prefetch); err != nil {
+			return nil, err
+		}
+		if config.Prefetches[queueName] < 1 {
+			return nil, fmt.Errorf("%s must be at least 1", queueEnvName(queueName, "PREFETCH"))
+		}
+	}
 	return config, nil
 }
+
+func loadUserLimits() (admission.UserLimits, error) {
+	var limits admission.UserLimits
+	var err error
+	if limits.MaxConcurrent, err = getEnvInt("USER_MAX_CONCURRENT", 0); err != nil {
+		return limits, err
+	}
+	if limits.RatePerMinute, err = getEnvFloat("USER_RATE_PER_MINUTE", 0); err != nil {
+		return limits, err
+	}
+	if limits.Burst, err = getEnvInt("USER_RATE_BURST", 10); err != nil {
+		return limits, err
+	}
+	return limits, nil
+}
+
+func loadIdempotencyOptions() (idempotency.Options, error) {
+	opts := idempotency.Options{
+		Backend:  os.Getenv("IDEMPOTENCY_STORE"),
+		BoltPath: os.Getenv("IDEMPOTENCY_BOLT_PATH"),
+		RedisURL: os.Getenv("REDIS_URL"),
+	}
+	if opts.BoltPath == "" {
+		opts.BoltPath = "idempotency.db"
+	}
+	var err error
+	if opts.Capacity, err = getEnvInt("IDEMPOTENCY_CAPACITY", 10000); err != nil {
+		return opts, err
+	}
+	if opts.TTL, err = getEnvDuration("IDEMPOTENCY_TTL", 24*time.Hour); err != nil {
+		return opts, err
+	}
+	return opts, nil
+}
+
+// loadRetryPolicy reads <prefix>_MAX_ATTEMPTS, _BASE_DELAY, _MAX_DELAY and _JITTER on top of def
+func loadRetryPolicy(prefix string, def retry.Policy) (retry.Policy, error) {
+	policy := def
+	var err error
+	if policy.MaxAttempts, err = getEnvInt(prefix+"_MAX_ATTEMPTS", def.MaxAttempts); err != nil {
+		return policy, err
+	}
+	if policy.BaseDelay, err = getEnvDuration(prefix+"_BASE_DELAY", def.BaseDelay); err != nil {
+		return policy, err
+	}
+	if policy.MaxDelay, err = getEnvDuration(prefix+"_MAX_DELAY", def.MaxDelay); err != nil {
+		return policy, err
+	}
+	if policy.Jitter, err = getEnvFloat(prefix+"_JITTER", def.Jitter); err != nil {
+		return policy, err
+	}
+	return policy, nil
+}
+
+func loadImageLimits() (validation.Limits, error) {
+	var limits validation.Limits
+	maxPixels, err := getEnvInt("MAX_IMAGE_PIXELS", 50_000_000)
+	if err != nil {
+		return limits, err
+	}
+	maxBytes, err := getEnvInt("MAX_IMAGE_BYTES", 50<<20)
+	if err != nil {
+		return limits, err
+	}
+	if limits.MaxFrames, err = getEnvInt("MAX_IMAGE_FRAMES", 100); err != nil {
+		return limits, err
+	}
+	limits.MaxPixels = int64(maxPixels)
+	limits.MaxBytes = int64(maxBytes)
+	return limits, nil
+}
+
+func loadInspectDefaults() (types.Inspect, error) {
+	var inspect types.Inspect
+	var err error
+	if inspect.MinSharpness, err = getEnvFloat("INSPECT_MIN_SHARPNESS", 100); err != nil {
+		return inspect, err
+	}
+	if inspect.MaxClipping, err = getEnvFloat("INSPECT_MAX_CLIPPING", 0.1); err != nil {
+		return inspect, err
+	}
+	if inspect.MinWidth, err = getEnvInt("INSPECT_MIN_WIDTH", 800); err != nil {
+		return inspect, err
+	}
+	if inspect.MinHeight, err = getEnvInt("INSPECT_MIN_HEIGHT", 800); err != nil {
+		return inspect, err
+	}
+	return inspect, nil
+}
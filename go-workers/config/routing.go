@@ -0,0 +1,126 @@
+package config
+
+import (
+	"fmt"
+	"hash/fnv"
+	"os"
+	"slices"
+	"strings"
+)
+
+// Routing modes: one queue per transformation type, or every job on a topic exchange
+const (
+	RoutingModeQueues = "queues"
+	RoutingModeTopic  = "topic"
+)
+
+// RoutingConfig describes where jobs come from and where statuses go.
+//
+// In queues mode the worker consumes every queue of RABBITMQ_QUEUES except the status queue. In topic
+// mode producers publish jobs to JobsExchange with the routing key JobRoutingKey returns, jobs.<type>, or
+// jobs.<type>.<shard> with the shard ShardFor picks when sharding, and the worker consumes JobsQueue, or
+// JobsQueue.0 .. JobsQueue.<n-1>, dispatching on the job's TransformationType.
+type RoutingConfig struct {
+	Mode             string
+	StatusQueue      string
+	StatusExchange   string
+	StatusRoutingKey string
+	JobsExchange     string
+	JobsQueue        string
+	JobsQueueShards  int
+}
+
+func loadRoutingConfig() (RoutingConfig, error) {
+	routing := RoutingConfig{
+		Mode:             getEnvString("ROUTING_MODE", RoutingModeQueues),
+		StatusQueue:      getEnvString("STATUS_QUEUE", "status_queue"),
+		StatusExchange:   getEnvString("STATUS_EXCHANGE", "image_processing"),
+		StatusRoutingKey: getEnvString("STATUS_ROUTING_KEY", "status"),
+		JobsExchange:     getEnvString("JOBS_EXCHANGE", "image_processing.jobs"),
+		JobsQueue:        getEnvString("JOBS_QUEUE", "jobs_queue"),
+	}
+	if routing.Mode != RoutingModeQueues && routing.Mode != RoutingModeTopic {
+		return routing, fmt.Errorf("ROUTING_MODE must be %s or %s, got %q", RoutingModeQueues, RoutingModeTopic, routing.Mode)
+	}
+	var err error
+	if routing.JobsQueueShards, err = getEnvInt("JOBS_QUEUE_SHARDS", 1); err != nil {
+		return routing, err
+	}
+	if routing.JobsQueueShards < 1 {
+		return routing, fmt.Errorf("JOBS_QUEUE_SHARDS must be at least 1")
+	}
+	return routing, nil
+}
+
+// loadQueues reads RABBITMQ_QUEUES, which only queues mode needs: topic mode derives its queues from the
+// jobs queue settings, see WorkQueues
+func loadQueues(routing RoutingConfig) ([]string, error) {
+	queues := os.Getenv("RABBITMQ_QUEUES")
+	if queues == "" {
+		if routing.Mode == RoutingModeQueues {
+			return nil, fmt.Errorf("RABBITMQ_QUEUES is missing, it is required when ROUTING_MODE is %s", RoutingModeQueues)
+		}
+		return nil, nil
+	}
+	queueNames := strings.Split(queues, ",")
+	for i := range queueNames {
+		queueNames[i] = strings.TrimSpace(queueNames[i])
+	}
+	return queueNames, nil
+}
+
+// ShardFor returns the shard of job id among shards: the 32-bit FNV-1a hash of the id modulo shards.
+// Producers must pick shards the same way, so every message of a job lands on the same shard queue.
+func ShardFor(jobID string, shards int) int {
+	if shards <= 1 {
+		return 0
+	}
+	hash := fnv.New32a()
+	hash.Write([]byte(jobID))
+	return int(hash.Sum32() % uint32(shards))
+}
+
+// JobRoutingKey is the routing key a producer publishes a job with on JobsExchange in topic mode
+func (routing RoutingConfig) JobRoutingKey(transformationType string, jobID string) string {
+	if routing.JobsQueueShards == 1 {
+		return fmt.Sprintf("jobs.%s", transformationType)
+	}
+	return fmt.Sprintf("jobs.%s.%d", transformationType, ShardFor(jobID, routing.JobsQueueShards))
+}
+
+// ShardQueueName names the queue of one shard, an unsharded jobs queue keeps its plain name
+func (routing RoutingConfig) ShardQueueName(shard int) string {
+	if routing.JobsQueueShards == 1 {
+		return routing.JobsQueue
+	}
+	return fmt.Sprintf("%s.%d", routing.JobsQueue, shard)
+}
+
+// ShardBindingKey is the binding of one shard on the jobs exchange
+func (routing RoutingConfig) ShardBindingKey(shard int) string {
+	if routing.JobsQueueShards == 1 {
+		return "jobs.#"
+	}
+	return fmt.Sprintf("jobs.*.%d", shard)
+}
+
+// WorkQueues returns the queues the worker consumes jobs from
+func (c *Config) WorkQueues() []string {
+	if c.Routing.Mode == RoutingModeTopic {
+		queues := make([]string, c.Routing.JobsQueueShards)
+		for shard := range queues {
+			queues[shard] = c.Routing.ShardQueueName(shard)
+		}
+		return queues
+	}
+	return slices.DeleteFunc(slices.Clone(c.RabbitMqQueues), func(queueName string) bool {
+		return queueName == c.Routing.StatusQueue
+	})
+}
+
+func getEnvString(name string, def string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+	return def
+}
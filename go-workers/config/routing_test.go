@@ -0,0 +1,164 @@
+package config
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestLoadRoutingConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		want    RoutingConfig
+		wantErr bool
+	}{
+		{
+			name: "defaults",
+			want: RoutingConfig{
+				Mode:             RoutingModeQueues,
+				StatusQueue:      "status_queue",
+				StatusExchange:   "image_processing",
+				StatusRoutingKey: "status",
+				JobsExchange:     "image_processing.jobs",
+				JobsQueue:        "jobs_queue",
+				JobsQueueShards:  1,
+			},
+		},
+		{
+			name: "sharded topic",
+			env:  map[string]string{"ROUTING_MODE": RoutingModeTopic, "JOBS_QUEUE": "jobs", "JOBS_QUEUE_SHARDS": "4"},
+			want: RoutingConfig{
+				Mode:             RoutingModeTopic,
+				StatusQueue:      "status_queue",
+				StatusExchange:   "image_processing",
+				StatusRoutingKey: "status",
+				JobsExchange:     "image_processing.jobs",
+				JobsQueue:        "jobs",
+				JobsQueueShards:  4,
+			},
+		},
+		{name: "unknown mode", env: map[string]string{"ROUTING_MODE": "fanout"}, wantErr: true},
+		{name: "no shards", env: map[string]string{"JOBS_QUEUE_SHARDS": "0"}, wantErr: true},
+		{name: "shards not a number", env: map[string]string{"JOBS_QUEUE_SHARDS": "many"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, name := range []string{"ROUTING_MODE", "STATUS_QUEUE", "STATUS_EXCHANGE", "STATUS_ROUTING_KEY", "JOBS_EXCHANGE", "JOBS_QUEUE", "JOBS_QUEUE_SHARDS"} {
+				t.Setenv(name, tt.env[name])
+			}
+			routing, err := loadRoutingConfig()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("loadRoutingConfig() error = %v, wantErr %t", err, tt.wantErr)
+			}
+			if !tt.wantErr && routing != tt.want {
+				t.Fatalf("loadRoutingConfig() = %+v, want %+v", routing, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadQueues(t *testing.T) {
+	tests := []struct {
+		name    string
+		mode    string
+		queues  string
+		want    []string
+		wantErr bool
+	}{
+		{name: "queues mode", mode: RoutingModeQueues, queues: "resize_queue, convert_queue,status_queue", want: []string{"resize_queue", "convert_queue", "status_queue"}},
+		{name: "queues mode requires them", mode: RoutingModeQueues, wantErr: true},
+		{name: "topic mode needs none", mode: RoutingModeTopic},
+		{name: "topic mode ignores them", mode: RoutingModeTopic, queues: "resize_queue", want: []string{"resize_queue"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("RABBITMQ_QUEUES", tt.queues)
+			queues, err := loadQueues(RoutingConfig{Mode: tt.mode})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("loadQueues() error = %v, wantErr %t", err, tt.wantErr)
+			}
+			if !slices.Equal(queues, tt.want) {
+				t.Fatalf("loadQueues() = %q, want %q", queues, tt.want)
+			}
+		})
+	}
+}
+
+func TestShardFor(t *testing.T) {
+	tests := []struct {
+		jobID  string
+		shards int
+		want   int
+	}{
+		// FNV-1a of "" is the offset basis 2166136261
+		{jobID: "", shards: 4, want: 1},
+		{jobID: "a", shards: 4, want: 0},
+		{jobID: "a", shards: 3, want: 1},
+		{jobID: "job-1", shards: 4, want: 2},
+		{jobID: "3f1c2a9e-8d4b-4f6a-9c2e-1b7d5e0a4c3f", shards: 3, want: 1},
+		{jobID: "job-1", shards: 1, want: 0},
+		{jobID: "job-1", shards: 0, want: 0},
+	}
+	for _, tt := range tests {
+		if got := ShardFor(tt.jobID, tt.shards); got != tt.want {
+			t.Errorf("ShardFor(%q, %d) = %d, want %d", tt.jobID, tt.shards, got, tt.want)
+		}
+	}
+}
+
+func TestJobRoutingKey(t *testing.T) {
+	tests := []struct {
+		name    string
+		routing RoutingConfig
+		want    string
+		binding string
+	}{
+		{name: "unsharded", routing: RoutingConfig{JobsQueueShards: 1}, want: "jobs.CONVERT", binding: "jobs.#"},
+		{name: "sharded", routing: RoutingConfig{JobsQueueShards: 4}, want: "jobs.CONVERT.2", binding: "jobs.*.2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.routing.JobRoutingKey("CONVERT", "job-1"); got != tt.want {
+				t.Fatalf("JobRoutingKey() = %q, want %q", got, tt.want)
+			}
+			// The shard queue the key lands on must be the one bound for it
+			if binding := tt.routing.ShardBindingKey(ShardFor("job-1", tt.routing.JobsQueueShards)); binding != tt.binding {
+				t.Fatalf("ShardBindingKey() = %q, want %q", binding, tt.binding)
+			}
+		})
+	}
+}
+
+func TestWorkQueues(t *testing.T) {
+	tests := []struct {
+		name   string
+		config Config
+		want   []string
+	}{
+		{
+			name: "queues mode skips the status queue",
+			config: Config{
+				RabbitMqQueues: []string{"resize_queue", "status_queue", "convert_queue"},
+				Routing:        RoutingConfig{Mode: RoutingModeQueues, StatusQueue: "status_queue"},
+			},
+			want: []string{"resize_queue", "convert_queue"},
+		},
+		{
+			name:   "unsharded topic",
+			config: Config{Routing: RoutingConfig{Mode: RoutingModeTopic, JobsQueue: "jobs", JobsQueueShards: 1}},
+			want:   []string{"jobs"},
+		},
+		{
+			name:   "sharded topic",
+			config: Config{Routing: RoutingConfig{Mode: RoutingModeTopic, JobsQueue: "jobs", JobsQueueShards: 3}},
+			want:   []string{"jobs.0", "jobs.1", "jobs.2"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.config.WorkQueues(); !slices.Equal(got, tt.want) {
+				t.Fatalf("WorkQueues() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,8 +1,70 @@
 package config
 
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/mahirjain10/go-workers/internal/transformation"
+)
+
+// Worker holds the default number of consumer goroutines per queue, WORKER_CONFIG_FILE and
+// <QUEUE>_WORKERS env vars override it
 var Worker = map[string]int{
 	"resize_queue":       1,
 	"convert_queue":      3,
 	"force_resize_queue": 1,
 	"rotate_queue":       1,
+	"inspect_queue":      1,
+	"histogram_queue":    1,
+}
+
+// TransformationTypes are the job types a worker knows, e.g. for per type settings like CONVERT_TIMEOUT:
+// every registered transformation plus the analysis jobs
+var TransformationTypes = append(transformation.Names(), "INSPECT", "HISTOGRAM")
+
+// LoadWorkerCounts resolves the worker count of every queue: the Worker default, then the JSON object of
+// WorkerConfigFile (e.g. {"convert_queue": 4}), then <QUEUE>_WORKERS. It is called again on reload, so
+// editing the config file is how counts change without a restart.
+func (c *Config) LoadWorkerCounts() (map[string]int, error) {
+	counts := make(map[string]int, len(c.WorkQueues()))
+	for _, queueName := range c.WorkQueues() {
+		count, ok := Worker[queueName]
+		if !ok {
+			count = 1
+		}
+		counts[queueName] = count
+	}
+
+	if c.WorkerConfigFile != "" {
+		raw, err := os.ReadFile(c.WorkerConfigFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read worker config file: %w", err)
+		}
+		fromFile := make(map[string]int)
+		if err := json.Unmarshal(raw, &fromFile); err != nil {
+			return nil, fmt.Errorf("failed to parse worker config file %s: %w", c.WorkerConfigFile, err)
+		}
+		for queueName, count := range fromFile {
+			if _, ok := counts[queueName]; !ok {
+				return nil, fmt.Errorf("worker config file %s: unknown queue %s", c.WorkerConfigFile, queueName)
+			}
+			counts[queueName] = count
+		}
+	}
+
+	for queueName := range counts {
+		count, err := getEnvInt(queueEnvName(queueName, "WORKERS"), counts[queueName])
+		if err != nil {
+			return nil, err
+		}
+		counts[queueName] = count
+	}
+
+	for queueName, count := range counts {
+		if count < 0 {
+			return nil, fmt.Errorf("worker count of %s must not be negative, got %d", queueName, count)
+		}
+	}
+	return counts, nil
 }
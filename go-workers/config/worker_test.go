@@ -0,0 +1,80 @@
+package config
+
+import (
+	"maps"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadWorkerCounts(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		env     map[string]string
+		want    map[string]int
+		wantErr bool
+	}{
+		{
+			name: "defaults",
+			want: map[string]int{"resize_queue": 1, "convert_queue": 3, "custom_queue": 1},
+		},
+		{
+			name: "file overrides defaults",
+			file: `{"convert_queue": 5, "custom_queue": 2}`,
+			want: map[string]int{"resize_queue": 1, "convert_queue": 5, "custom_queue": 2},
+		},
+		{
+			name: "env overrides the file",
+			file: `{"convert_queue": 5}`,
+			env:  map[string]string{"CONVERT_QUEUE_WORKERS": "7", "RESIZE_QUEUE_WORKERS": "0"},
+			want: map[string]int{"resize_queue": 0, "convert_queue": 7, "custom_queue": 1},
+		},
+		{name: "unknown queue in the file", file: `{"sharpen_queue": 2}`, wantErr: true},
+		{name: "malformed file", file: `{"convert_queue": "two"}`, wantErr: true},
+		{name: "negative count", env: map[string]string{"CUSTOM_QUEUE_WORKERS": "-1"}, wantErr: true},
+		{name: "count that is not a number", env: map[string]string{"CUSTOM_QUEUE_WORKERS": "many"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+			config := &Config{
+				RabbitMqQueues: []string{"resize_queue", "convert_queue", "custom_queue", "status_queue"},
+				Routing:        RoutingConfig{Mode: RoutingModeQueues, StatusQueue: "status_queue"},
+			}
+			if tt.file != "" {
+				config.WorkerConfigFile = filepath.Join(t.TempDir(), "workers.json")
+				if err := os.WriteFile(config.WorkerConfigFile, []byte(tt.file), 0600); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			counts, err := config.LoadWorkerCounts()
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("LoadWorkerCounts() = %v, want an error", counts)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadWorkerCounts() = %v", err)
+			}
+			if !maps.Equal(counts, tt.want) {
+				t.Fatalf("LoadWorkerCounts() = %v, want %v", counts, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadWorkerCountsMissingFile(t *testing.T) {
+	config := &Config{
+		RabbitMqQueues:   []string{"resize_queue"},
+		Routing:          RoutingConfig{Mode: RoutingModeQueues, StatusQueue: "status_queue"},
+		WorkerConfigFile: filepath.Join(t.TempDir(), "missing.json"),
+	}
+	if _, err := config.LoadWorkerCounts(); err == nil {
+		t.Fatal("LoadWorkerCounts() with a missing file succeeded")
+	}
+}
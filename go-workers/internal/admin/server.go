@@ -0,0 +1,93 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"expvar"
+	"log"
+	"net/http"
+	"time"
+)
+
+// WorkerScaler is what the admin endpoint needs from the consumer side
+type WorkerScaler interface {
+	WorkerCounts() map[string]int
+	ScaleWorkers(queueName string, count int) error
+	ReloadWorkers() error
+}
+
+// Server is a small HTTP endpoint to inspect and change worker counts at runtime:
+//
+//	GET  /workers          current count per queue
+//	PUT  /workers/{queue}  body {"count": 4}
+//	POST /workers/reload   re-read WORKER_CONFIG_FILE and the env
+//	GET  /debug/vars       expvar metrics, e.g. queue_latency_seconds
+type Server struct {
+	server *http.Server
+	scaler WorkerScaler
+}
+
+func NewServer(addr string, scaler WorkerScaler) *Server {
+	server := &Server{scaler: scaler}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /workers", server.getWorkers)
+	mux.HandleFunc("PUT /workers/{queue}", server.scaleWorkers)
+	mux.HandleFunc("POST /workers/reload", server.reloadWorkers)
+	mux.Handle("GET /debug/vars", expvar.Handler())
+	server.server = &http.Server{
+		Addr:              addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+	return server
+}
+
+// Start serves in the background until Shutdown
+func (server *Server) Start() {
+	go func() {
+		log.Printf("[admin] listening on %s", server.server.Addr)
+		if err := server.server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("[admin] server stopped: %v", err)
+		}
+	}()
+}
+
+func (server *Server) Shutdown(ctx context.Context) error {
+	return server.server.Shutdown(ctx)
+}
+
+func (server *Server) getWorkers(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, server.scaler.WorkerCounts())
+}
+
+func (server *Server) scaleWorkers(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Count *int `json:"count"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.Count == nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": `expected a body like {"count": 4}`})
+		return
+	}
+	if err := server.scaler.ScaleWorkers(r.PathValue("queue"), *body.Count); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, server.scaler.WorkerCounts())
+}
+
+func (server *Server) reloadWorkers(w http.ResponseWriter, r *http.Request) {
+	if err := server.scaler.ReloadWorkers(); err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, server.scaler.WorkerCounts())
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		log.Printf("[admin] failed to write response: %v", err)
+	}
+}
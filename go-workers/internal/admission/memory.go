@@ -0,0 +1,58 @@
+package admission
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"log"
+
+	"golang.org/x/sync/semaphore"
+)
+
+// decodeOverheadFactor accounts for the decoded source plus the working copy and output
+// most transformations allocate next to it
+const decodeOverheadFactor = 3
+
+// bytesPerPixel is the size of a decoded NRGBA pixel
+const bytesPerPixel = 4
+
+// ErrBudgetUnavailable is returned when ctx is done before the job fit in the budget
+var ErrBudgetUnavailable = errors.New("memory budget unavailable")
+
+// MemoryBudget admits jobs only while the sum of their estimated decoded sizes stays within the budget
+type MemoryBudget struct {
+	budget int64
+	sem    *semaphore.Weighted
+}
+
+// NewMemoryBudget creates a budget of the given bytes, a budget <= 0 admits everything
+func NewMemoryBudget(budget int64) *MemoryBudget {
+	memoryBudget := &MemoryBudget{budget: budget}
+	if budget > 0 {
+		memoryBudget.sem = semaphore.NewWeighted(budget)
+	}
+	return memoryBudget
+}
+
+// EstimateDecodedSize estimates the memory a job working on an image of cfg will need
+func EstimateDecodedSize(cfg image.Config) int64 {
+	return int64(cfg.Width) * int64(cfg.Height) * bytesPerPixel * decodeOverheadFactor
+}
+
+// Acquire blocks until weight bytes fit in the budget or ctx is done, the returned func releases them.
+// A weight larger than the whole budget is clamped so the job runs alone instead of waiting forever.
+func (m *MemoryBudget) Acquire(ctx context.Context, weight int64) (func(), error) {
+	if m.sem == nil || weight <= 0 {
+		return func() {}, nil
+	}
+	weight = min(weight, m.budget)
+
+	if !m.sem.TryAcquire(weight) {
+		log.Printf("[admission] waiting for %d bytes of memory budget", weight)
+		if err := m.sem.Acquire(ctx, weight); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrBudgetUnavailable, err)
+		}
+	}
+	return func() { m.sem.Release(weight) }, nil
+}
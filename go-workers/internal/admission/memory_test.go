@@ -0,0 +1,143 @@
+package admission
+
+import (
+	"context"
+	"errors"
+	"image"
+	"testing"
+	"time"
+)
+
+// acquired is what an Acquire running in the background returned
+type acquired struct {
+	release func()
+	err     error
+}
+
+// acquireAsync runs Acquire in the background, the channel receives its result once it returns
+func acquireAsync(ctx context.Context, budget *MemoryBudget, weight int64) <-chan acquired {
+	result := make(chan acquired, 1)
+	go func() {
+		release, err := budget.Acquire(ctx, weight)
+		result <- acquired{release: release, err: err}
+	}()
+	return result
+}
+
+// blocked reports whether result stays empty for a short while, i.e. Acquire is still waiting
+func blocked(result <-chan acquired) bool {
+	select {
+	case <-result:
+		return false
+	case <-time.After(20 * time.Millisecond):
+		return true
+	}
+}
+
+func mustAcquire(t *testing.T, budget *MemoryBudget, weight int64) func() {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	release, err := budget.Acquire(ctx, weight)
+	if err != nil {
+		t.Fatalf("Acquire(%d) = %v", weight, err)
+	}
+	return release
+}
+
+func TestMemoryBudgetAcquireRelease(t *testing.T) {
+	budget := NewMemoryBudget(100)
+	first := mustAcquire(t, budget, 60)
+	second := mustAcquire(t, budget, 40)
+	first()
+	second()
+	// Everything was given back, so the whole budget fits again
+	mustAcquire(t, budget, 100)()
+}
+
+func TestMemoryBudgetBlocksWhenOverBudget(t *testing.T) {
+	budget := NewMemoryBudget(100)
+	release := mustAcquire(t, budget, 80)
+
+	result := acquireAsync(context.Background(), budget, 40)
+	if !blocked(result) {
+		t.Fatal("Acquire over the budget returned before anything was released")
+	}
+	release()
+	waiting := <-result
+	if waiting.err != nil {
+		t.Fatalf("Acquire() = %v after the budget was released", waiting.err)
+	}
+	waiting.release()
+}
+
+func TestMemoryBudgetClampsWeightLargerThanBudget(t *testing.T) {
+	budget := NewMemoryBudget(100)
+	release := mustAcquire(t, budget, 500)
+
+	// The oversized job holds the whole budget, so it runs alone
+	result := acquireAsync(context.Background(), budget, 1)
+	if !blocked(result) {
+		t.Fatal("Acquire returned while an oversized job held the budget")
+	}
+	release()
+	waiting := <-result
+	if waiting.err != nil {
+		t.Fatalf("Acquire() = %v after the budget was released", waiting.err)
+	}
+	waiting.release()
+}
+
+func TestMemoryBudgetCancelWhileWaiting(t *testing.T) {
+	budget := NewMemoryBudget(100)
+	release := mustAcquire(t, budget, 100)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	result := acquireAsync(ctx, budget, 10)
+	if !blocked(result) {
+		t.Fatal("Acquire over the budget returned before ctx was cancelled")
+	}
+	cancel()
+	if waiting := <-result; !errors.Is(waiting.err, ErrBudgetUnavailable) {
+		t.Fatalf("Acquire() = %v, want ErrBudgetUnavailable", waiting.err)
+	}
+
+	// The cancelled wait must not have taken any of the budget
+	release()
+	mustAcquire(t, budget, 100)()
+}
+
+func TestMemoryBudgetDisabled(t *testing.T) {
+	for _, size := range []int64{0, -1} {
+		budget := NewMemoryBudget(size)
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		// A disabled budget admits everything, even on a done ctx
+		release, err := budget.Acquire(ctx, 1<<40)
+		if err != nil {
+			t.Fatalf("budget %d: Acquire() = %v", size, err)
+		}
+		release()
+	}
+}
+
+func TestEstimateDecodedSize(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  image.Config
+		want int64
+	}{
+		{name: "empty", cfg: image.Config{}, want: 0},
+		{name: "one pixel", cfg: image.Config{Width: 1, Height: 1}, want: bytesPerPixel * decodeOverheadFactor},
+		{name: "12 megapixels", cfg: image.Config{Width: 4000, Height: 3000}, want: 4000 * 3000 * bytesPerPixel * decodeOverheadFactor},
+		// 65535 x 65535 overflows int32 once multiplied, the estimate is computed in int64
+		{name: "largest PNG header", cfg: image.Config{Width: 65535, Height: 65535}, want: 65535 * 65535 * bytesPerPixel * decodeOverheadFactor},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := EstimateDecodedSize(tt.cfg); got != tt.want {
+				t.Fatalf("EstimateDecodedSize() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package admission
+
+import (
+	"sync"
+	"time"
+)
+
+// UserLimits bound what a single user gets out of one worker process: at most MaxConcurrent jobs at a
+// time, and a token bucket refilled at RatePerMinute jobs per minute holding up to Burst tokens.
+// A zero MaxConcurrent or RatePerMinute disables that limit.
+type UserLimits struct {
+	MaxConcurrent int
+	RatePerMinute float64
+	Burst         int
+}
+
+// Enabled reports whether any limit applies
+func (limits UserLimits) Enabled() bool {
+	return limits.MaxConcurrent > 0 || limits.RatePerMinute > 0
+}
+
+// pruneInterval is how often users with nothing running and a full bucket are forgotten
+const pruneInterval = time.Minute
+
+type userState struct {
+	active int
+	tokens float64
+	last   time.Time
+}
+
+// UserLimiter keeps one user from starving the others, it is safe for concurrent use
+type UserLimiter struct {
+	limits UserLimits
+
+	mu         sync.Mutex
+	users      map[string]*userState
+	lastPruned time.Time
+	// now is time.Now, tests replace it to drive the token buckets
+	now func() time.Time
+}
+
+func NewUserLimiter(limits UserLimits) *UserLimiter {
+	return &UserLimiter{
+		limits:     limits,
+		users:      make(map[string]*userState),
+		lastPruned: time.Now(),
+		now:        time.Now,
+	}
+}
+
+// Admit takes a slot and a token for userID when both are available, the returned func gives the slot
+// back once the job is done. ok is false when the user is over a limit and the job should wait.
+func (limiter *UserLimiter) Admit(userID string) (release func(), ok bool) {
+	if !limiter.limits.Enabled() {
+		return func() {}, true
+	}
+
+	limiter.mu.Lock()
+	defer limiter.mu.Unlock()
+
+	now := limiter.now()
+	limiter.prune(now)
+
+	user, found := limiter.users[userID]
+	if !found {
+		user = &userState{tokens: float64(limiter.burst()), last: now}
+		limiter.users[userID] = user
+	}
+	limiter.refill(user, now)
+
+	if limiter.limits.MaxConcurrent > 0 && user.active >= limiter.limits.MaxConcurrent {
+		return nil, false
+	}
+	if limiter.limits.RatePerMinute > 0 {
+		if user.tokens < 1 {
+			return nil, false
+		}
+		user.tokens--
+	}
+	user.active++
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			limiter.mu.Lock()
+			user.active--
+			limiter.mu.Unlock()
+		})
+	}, true
+}
+
+func (limiter *UserLimiter) burst() int {
+	return max(limiter.limits.Burst, 1)
+}
+
+func (limiter *UserLimiter) refill(user *userState, now time.Time) {
+	if limiter.limits.RatePerMinute <= 0 {
+		return
+	}
+	elapsed := now.Sub(user.last).Minutes()
+	user.tokens = min(user.tokens+elapsed*limiter.limits.RatePerMinute, float64(limiter.burst()))
+	user.last = now
+}
+
+// prune drops idle users whose bucket is full again, they would start from the same state anyway
+func (limiter *UserLimiter) prune(now time.Time) {
+	if now.Sub(limiter.lastPruned) < pruneInterval {
+		return
+	}
+	limiter.lastPruned = now
+	for userID, user := range limiter.users {
+		if user.active > 0 {
+			continue
+		}
+		limiter.refill(user, now)
+		if limiter.limits.RatePerMinute <= 0 || user.tokens >= float64(limiter.burst()) {
+			delete(limiter.users, userID)
+		}
+	}
+}
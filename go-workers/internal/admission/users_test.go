@@ -0,0 +1,149 @@
+package admission
+
+import (
+	"testing"
+	"time"
+)
+
+// fakeClock drives the token buckets of a limiter
+type fakeClock struct {
+	now time.Time
+}
+
+func (clock *fakeClock) advance(d time.Duration) {
+	clock.now = clock.now.Add(d)
+}
+
+func newTestLimiter(limits UserLimits) (*UserLimiter, *fakeClock) {
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	limiter := NewUserLimiter(limits)
+	limiter.now = func() time.Time { return clock.now }
+	limiter.lastPruned = clock.now
+	return limiter, clock
+}
+
+func TestUserLimiter(t *testing.T) {
+	type step struct {
+		user    string
+		advance time.Duration
+		// release gives back the slot of the admitted job with that index
+		release int
+		want    bool
+	}
+	noRelease := -1
+	tests := []struct {
+		name   string
+		limits UserLimits
+		steps  []step
+	}{
+		{
+			name:   "disabled limiter always admits",
+			limits: UserLimits{},
+			steps: []step{
+				{user: "a", release: noRelease, want: true},
+				{user: "a", release: noRelease, want: true},
+				{user: "a", release: noRelease, want: true},
+			},
+		},
+		{
+			name:   "concurrency limit blocks until a slot is released",
+			limits: UserLimits{MaxConcurrent: 2},
+			steps: []step{
+				{user: "a", release: noRelease, want: true},
+				{user: "a", release: noRelease, want: true},
+				{user: "a", release: noRelease, want: false},
+				{user: "a", release: 0, want: true},
+				{user: "a", release: noRelease, want: false},
+			},
+		},
+		{
+			name:   "releasing twice frees one slot",
+			limits: UserLimits{MaxConcurrent: 1},
+			steps: []step{
+				{user: "a", release: noRelease, want: true},
+				{user: "a", release: 0, want: true},
+				{user: "a", release: 0, want: false},
+			},
+		},
+		{
+			name:   "burst is used up then refills",
+			limits: UserLimits{RatePerMinute: 2, Burst: 2},
+			steps: []step{
+				{user: "a", release: noRelease, want: true},
+				{user: "a", release: noRelease, want: true},
+				{user: "a", release: noRelease, want: false},
+				{user: "a", advance: 20 * time.Second, release: noRelease, want: false},
+				{user: "a", advance: 10 * time.Second, release: noRelease, want: true},
+				{user: "a", release: noRelease, want: false},
+			},
+		},
+		{
+			name:   "zero burst still admits one job",
+			limits: UserLimits{RatePerMinute: 60},
+			steps: []step{
+				{user: "a", release: noRelease, want: true},
+				{user: "a", release: noRelease, want: false},
+				{user: "a", advance: time.Second, release: noRelease, want: true},
+			},
+		},
+		{
+			name:   "refill is capped at the burst",
+			limits: UserLimits{RatePerMinute: 60, Burst: 2},
+			steps: []step{
+				{user: "a", release: noRelease, want: true},
+				{user: "a", advance: time.Hour, release: noRelease, want: true},
+				{user: "a", release: noRelease, want: true},
+				{user: "a", release: noRelease, want: false},
+			},
+		},
+		{
+			name:   "users are limited independently",
+			limits: UserLimits{MaxConcurrent: 1, RatePerMinute: 1, Burst: 1},
+			steps: []step{
+				{user: "a", release: noRelease, want: true},
+				{user: "a", release: noRelease, want: false},
+				{user: "b", release: noRelease, want: true},
+				{user: "b", release: noRelease, want: false},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limiter, clock := newTestLimiter(tt.limits)
+			var releases []func()
+			for i, step := range tt.steps {
+				clock.advance(step.advance)
+				if step.release >= 0 {
+					releases[step.release]()
+				}
+				release, ok := limiter.Admit(step.user)
+				if ok != step.want {
+					t.Fatalf("step %d: Admit(%q) = %t, want %t", i, step.user, ok, step.want)
+				}
+				if ok {
+					releases = append(releases, release)
+				}
+			}
+		})
+	}
+}
+
+func TestUserLimiterPrunesIdleUsers(t *testing.T) {
+	limiter, clock := newTestLimiter(UserLimits{MaxConcurrent: 1, RatePerMinute: 60, Burst: 1})
+
+	releaseIdle, _ := limiter.Admit("idle")
+	releaseIdle()
+	if _, ok := limiter.Admit("busy"); !ok {
+		t.Fatal("Admit(busy) = false, want true")
+	}
+
+	clock.advance(pruneInterval)
+	limiter.Admit("other")
+
+	if _, found := limiter.users["idle"]; found {
+		t.Error("idle user with a full bucket was not pruned")
+	}
+	if _, found := limiter.users["busy"]; !found {
+		t.Error("user with a running job was pruned")
+	}
+}
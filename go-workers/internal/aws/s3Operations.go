@@ -13,6 +13,7 @@ import (
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/mahirjain10/go-workers/internal/retry"
 	"github.com/mahirjain10/go-workers/internal/utils"
 )
 
@@ -22,11 +23,17 @@ type S3Service struct {
 	bucketName   string
 	uploadPath   string
 	downloadPath string
+	// retryPolicy is used by every operation unless the context carries a queue specific one
+	retryPolicy retry.Policy
 }
 
 // Using Constructor Pattern to initalize our s3Service
-func NewS3Service(client *s3.Client, bucketName string, downloadPath string, uploadPath string) *S3Service {
-	return &S3Service{client: client, bucketName: bucketName, downloadPath: downloadPath, uploadPath: uploadPath}
+func NewS3Service(client *s3.Client, bucketName string, downloadPath string, uploadPath string, retryPolicy retry.Policy) *S3Service {
+	return &S3Service{client: client, bucketName: bucketName, downloadPath: downloadPath, uploadPath: uploadPath, retryPolicy: retryPolicy}
+}
+
+func (service *S3Service) policy(ctx context.Context) retry.Policy {
+	return retry.FromContext(ctx, service.retryPolicy)
 }
 
 func (s3Service *S3Service) GetDependencyData() (string, string, string) {
@@ -34,6 +41,12 @@ func (s3Service *S3Service) GetDependencyData() (string, string, string) {
 }
 
 func (service *S3Service) DownloadFromS3Object(ctx context.Context, key string) error {
+	return service.policy(ctx).Do(ctx, "download "+key, func(ctx context.Context) error {
+		return service.downloadFromS3Object(ctx, key)
+	})
+}
+
+func (service *S3Service) downloadFromS3Object(ctx context.Context, key string) error {
 	ctx, cancel := context.WithTimeout(ctx, 1*time.Second)
 	defer cancel()
 
@@ -64,7 +77,17 @@ func (service *S3Service) DownloadFromS3Object(ctx context.Context, key string)
 	return nil
 }
 
-func (service *S3Service) UploadtoS3Object(parentCtx context.Context, key string) (string, error) {
+func (service *S3Service) UploadtoS3Object(ctx context.Context, key string) (string, error) {
+	var publicUrl string
+	err := service.policy(ctx).Do(ctx, "upload "+key, func(ctx context.Context) error {
+		var err error
+		publicUrl, err = service.uploadtoS3Object(ctx, key)
+		return err
+	})
+	return publicUrl, err
+}
+
+func (service *S3Service) uploadtoS3Object(parentCtx context.Context, key string) (string, error) {
 
 	// return "",fmt.Errorf("error") // To test if we are failure is working or not
 
@@ -119,16 +142,22 @@ func (service *S3Service) DeleteS3Object(parentCtx context.Context, key string)
 		return false, errors.New("key cannot be empty")
 	}
 
-	ctx, cancel := context.WithTimeout(parentCtx, 1*time.Minute)
-	defer cancel()
-
 	deleteInput := &s3.DeleteObjectInput{
 		Bucket: aws.String(service.bucketName),
 		Key:    aws.String(key),
 	}
 
-	if _, err := service.client.DeleteObject(ctx, deleteInput); err != nil {
-		return false, fmt.Errorf("failed to delete object %s: %w", key, err)
+	err := service.policy(parentCtx).Do(parentCtx, "delete "+key, func(parentCtx context.Context) error {
+		ctx, cancel := context.WithTimeout(parentCtx, 1*time.Minute)
+		defer cancel()
+
+		if _, err := service.client.DeleteObject(ctx, deleteInput); err != nil {
+			return fmt.Errorf("failed to delete object %s: %w", key, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return false, err
 	}
 
 	return true, nil
@@ -0,0 +1,148 @@
+package idempotency
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/mahirjain10/go-workers/internal/types"
+	"github.com/mahirjain10/go-workers/internal/utils"
+	bolt "go.etcd.io/bbolt"
+)
+
+var completedBucket = []byte("completed_jobs")
+
+// boltPruneInterval is how often Put sweeps expired entries out of the file
+const boltPruneInterval = time.Hour
+
+// boltEntry is a stored job, CompletedAt is when it was recorded
+type boltEntry struct {
+	CompletedAt time.Time         `json:"completedAt"`
+	Status      *types.StatusData `json:"statusData"`
+}
+
+// BoltStore keeps completed jobs in a local bbolt file, it survives restarts of the worker but is not
+// shared between replicas. Like the Redis store, entries expire after ttl, a zero ttl keeps them forever.
+type BoltStore struct {
+	db  *bolt.DB
+	ttl time.Duration
+
+	mu         sync.Mutex
+	lastPruned time.Time
+}
+
+func NewBoltStore(path string, ttl time.Duration) (*BoltStore, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open idempotency store %s: %w", path, err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(completedBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create idempotency bucket: %w", err)
+	}
+	store := &BoltStore{db: db, ttl: ttl}
+	if err := store.prune(time.Now()); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return store, nil
+}
+
+func (b *BoltStore) Get(ctx context.Context, id string) (*types.StatusData, bool, error) {
+	var value []byte
+	err := b.db.View(func(tx *bolt.Tx) error {
+		if stored := tx.Bucket(completedBucket).Get([]byte(id)); stored != nil {
+			value = append([]byte(nil), stored...)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read job %s: %w", id, err)
+	}
+	if value == nil {
+		return nil, false, nil
+	}
+
+	var entry boltEntry
+	if err := utils.ParseJSON(value, &entry); err != nil {
+		return nil, false, err
+	}
+	if b.expired(entry, time.Now()) {
+		return nil, false, nil
+	}
+	return entry.Status, true, nil
+}
+
+func (b *BoltStore) Put(ctx context.Context, id string, statusData *types.StatusData) error {
+	now := time.Now()
+	value, err := utils.SerializeJSON(boltEntry{CompletedAt: now, Status: statusData})
+	if err != nil {
+		return fmt.Errorf("failed to serialize job %s: %w", id, err)
+	}
+	err = b.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(completedBucket).Put([]byte(id), value)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record job %s: %w", id, err)
+	}
+
+	b.mu.Lock()
+	due := now.Sub(b.lastPruned) >= boltPruneInterval
+	b.mu.Unlock()
+	if due {
+		// The job is recorded, a failed sweep is retried on a later Put
+		if err := b.prune(now); err != nil {
+			log.Printf("failed to prune idempotency store: %v", err)
+		}
+	}
+	return nil
+}
+
+func (b *BoltStore) expired(entry boltEntry, now time.Time) bool {
+	return b.ttl > 0 && now.Sub(entry.CompletedAt) >= b.ttl
+}
+
+// prune deletes the expired entries, the file would otherwise grow with every job ever processed
+func (b *BoltStore) prune(now time.Time) error {
+	b.mu.Lock()
+	b.lastPruned = now
+	b.mu.Unlock()
+	if b.ttl <= 0 {
+		return nil
+	}
+
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(completedBucket)
+		var stale [][]byte
+		err := bucket.ForEach(func(key, value []byte) error {
+			var entry boltEntry
+			if err := utils.ParseJSON(value, &entry); err != nil || b.expired(entry, now) {
+				stale = append(stale, append([]byte(nil), key...))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, key := range stale {
+			if err := bucket.Delete(key); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to prune expired jobs: %w", err)
+	}
+	return nil
+}
+
+func (b *BoltStore) Close() error {
+	return b.db.Close()
+}
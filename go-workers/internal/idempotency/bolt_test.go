@@ -0,0 +1,83 @@
+package idempotency
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mahirjain10/go-workers/internal/types"
+	bolt "go.etcd.io/bbolt"
+)
+
+func TestBoltStoreExpiry(t *testing.T) {
+	tests := []struct {
+		name     string
+		ttl      time.Duration
+		age      time.Duration
+		wantOk   bool
+		wantKept bool
+	}{
+		{name: "fresh entry", ttl: time.Hour, age: time.Minute, wantOk: true, wantKept: true},
+		{name: "expired entry", ttl: time.Hour, age: 2 * time.Hour, wantOk: false, wantKept: false},
+		{name: "zero ttl keeps entries", ttl: 0, age: 365 * 24 * time.Hour, wantOk: true, wantKept: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "idempotency.db")
+			store, err := NewBoltStore(path, tt.ttl)
+			if err != nil {
+				t.Fatal(err)
+			}
+			status := &types.StatusData{ID: "job-1", UserID: "user-1", Status: "PROCESSED"}
+			if err := store.Put(context.Background(), "job-1", status); err != nil {
+				t.Fatal(err)
+			}
+			backdate(t, store.db, "job-1", status, tt.age)
+
+			got, ok, err := store.Get(context.Background(), "job-1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ok != tt.wantOk {
+				t.Fatalf("Get() ok = %t, want %t", ok, tt.wantOk)
+			}
+			if ok && *got != *status {
+				t.Fatalf("Get() = %+v, want %+v", got, status)
+			}
+
+			// Reopening sweeps the file
+			store.Close()
+			store, err = NewBoltStore(path, tt.ttl)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer store.Close()
+			var kept bool
+			store.db.View(func(tx *bolt.Tx) error {
+				kept = tx.Bucket(completedBucket).Get([]byte("job-1")) != nil
+				return nil
+			})
+			if kept != tt.wantKept {
+				t.Fatalf("entry kept after reopen = %t, want %t", kept, tt.wantKept)
+			}
+		})
+	}
+}
+
+// backdate rewrites the entry of id as if it was recorded age ago
+func backdate(t *testing.T, db *bolt.DB, id string, status *types.StatusData, age time.Duration) {
+	t.Helper()
+	entry := boltEntry{CompletedAt: time.Now().Add(-age), Status: status}
+	err := db.Update(func(tx *bolt.Tx) error {
+		value, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+		return tx.Bucket(completedBucket).Put([]byte(id), value)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
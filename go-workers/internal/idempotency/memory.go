@@ -0,0 +1,71 @@
+package idempotency
+
+import (
+	"container/list"
+	"context"
+	"sync"
+
+	"github.com/mahirjain10/go-workers/internal/types"
+)
+
+const defaultCapacity = 10000
+
+type memoryEntry struct {
+	id         string
+	statusData types.StatusData
+}
+
+// MemoryStore is an in-process LRU, it only protects against redeliveries to the same worker process
+type MemoryStore struct {
+	mu       sync.Mutex
+	capacity int
+	order    *list.List
+	entries  map[string]*list.Element
+}
+
+func NewMemoryStore(capacity int) *MemoryStore {
+	if capacity <= 0 {
+		capacity = defaultCapacity
+	}
+	return &MemoryStore{
+		capacity: capacity,
+		order:    list.New(),
+		entries:  make(map[string]*list.Element, capacity),
+	}
+}
+
+func (m *MemoryStore) Get(ctx context.Context, id string) (*types.StatusData, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	element, ok := m.entries[id]
+	if !ok {
+		return nil, false, nil
+	}
+	m.order.MoveToFront(element)
+	statusData := element.Value.(*memoryEntry).statusData
+	return &statusData, true, nil
+}
+
+func (m *MemoryStore) Put(ctx context.Context, id string, statusData *types.StatusData) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if element, ok := m.entries[id]; ok {
+		element.Value.(*memoryEntry).statusData = *statusData
+		m.order.MoveToFront(element)
+		return nil
+	}
+
+	m.entries[id] = m.order.PushFront(&memoryEntry{id: id, statusData: *statusData})
+	if m.order.Len() > m.capacity {
+		oldest := m.order.Back()
+		m.order.Remove(oldest)
+		delete(m.entries, oldest.Value.(*memoryEntry).id)
+	}
+	return nil
+}
+
+func (m *MemoryStore) Close() error {
+	return nil
+}
@@ -0,0 +1,70 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/mahirjain10/go-workers/internal/types"
+	"github.com/mahirjain10/go-workers/internal/utils"
+	"github.com/redis/go-redis/v9"
+)
+
+const redisKeyPrefix = "image_processing:completed:"
+
+// RedisStore shares completed jobs between every worker replica, entries expire after ttl
+type RedisStore struct {
+	client *redis.Client
+	ttl    time.Duration
+}
+
+func NewRedisStore(url string, ttl time.Duration) (*RedisStore, error) {
+	if url == "" {
+		return nil, fmt.Errorf("REDIS_URL is required for the redis idempotency store")
+	}
+	opts, err := redis.ParseURL(url)
+	if err != nil {
+		return nil, fmt.Errorf("invalid redis url: %w", err)
+	}
+	client := redis.NewClient(opts)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to connect to redis: %w", err)
+	}
+	return &RedisStore{client: client, ttl: ttl}, nil
+}
+
+func (r *RedisStore) Get(ctx context.Context, id string) (*types.StatusData, bool, error) {
+	value, err := r.client.Get(ctx, redisKeyPrefix+id).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read job %s: %w", id, err)
+	}
+
+	var statusData types.StatusData
+	if err := utils.ParseJSON(value, &statusData); err != nil {
+		return nil, false, err
+	}
+	return &statusData, true, nil
+}
+
+func (r *RedisStore) Put(ctx context.Context, id string, statusData *types.StatusData) error {
+	value, err := utils.SerializeJSON(statusData)
+	if err != nil {
+		return fmt.Errorf("failed to serialize job %s: %w", id, err)
+	}
+	if err := r.client.Set(ctx, redisKeyPrefix+id, value, r.ttl).Err(); err != nil {
+		return fmt.Errorf("failed to record job %s: %w", id, err)
+	}
+	return nil
+}
+
+func (r *RedisStore) Close() error {
+	return r.client.Close()
+}
@@ -0,0 +1,60 @@
+package idempotency
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/mahirjain10/go-workers/internal/types"
+)
+
+// Store remembers the final status of completed jobs keyed on ImageProcessing.Id, so a redelivered
+// message can be acked and its previous result re-published instead of being processed again
+type Store interface {
+	// Get returns the recorded status of the job, ok is false when the job never completed
+	Get(ctx context.Context, id string) (*types.StatusData, bool, error)
+	// Put records the final status of a completed job
+	Put(ctx context.Context, id string, statusData *types.StatusData) error
+	Close() error
+}
+
+// Options selects and configures a Store, see NewStore
+type Options struct {
+	// Backend is one of "memory", "bolt", "redis" or "none"
+	Backend  string
+	Capacity int
+	BoltPath string
+	RedisURL string
+	TTL      time.Duration
+}
+
+// NewStore creates the store selected by opts.Backend
+func NewStore(opts Options) (Store, error) {
+	switch opts.Backend {
+	case "", "memory":
+		return NewMemoryStore(opts.Capacity), nil
+	case "bolt":
+		return NewBoltStore(opts.BoltPath, opts.TTL)
+	case "redis":
+		return NewRedisStore(opts.RedisURL, opts.TTL)
+	case "none":
+		return NopStore{}, nil
+	default:
+		return nil, fmt.Errorf("unknown idempotency store %q, expected memory, bolt, redis or none", opts.Backend)
+	}
+}
+
+// NopStore never remembers anything, every delivery is processed
+type NopStore struct{}
+
+func (NopStore) Get(ctx context.Context, id string) (*types.StatusData, bool, error) {
+	return nil, false, nil
+}
+
+func (NopStore) Put(ctx context.Context, id string, statusData *types.StatusData) error {
+	return nil
+}
+
+func (NopStore) Close() error {
+	return nil
+}
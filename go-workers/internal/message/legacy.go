@@ -0,0 +1,73 @@
+package message
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/mahirjain10/go-workers/internal/types"
+	"github.com/mahirjain10/go-workers/internal/utils"
+)
+
+// legacyEnvelope is what the NestJS ClientProxy emits today: the pattern and the ImageProcessing row
+// as Prisma returns it
+type legacyEnvelope struct {
+	Pattern string          `json:"pattern"`
+	Data    json.RawMessage `json:"data"`
+}
+
+// legacyJob lists the columns of the Prisma row the worker reads. The row is the API's table, not a
+// message schema, so columns the worker does not read are ignored and the table can grow freely.
+type legacyJob struct {
+	Id                       string          `json:"id"`
+	UserId                   string          `json:"userId"`
+	FileName                 string          `json:"filename"`
+	S3RawKey                 string          `json:"s3RawKey"`
+	Status                   string          `json:"status"`
+	TransformationType       string          `json:"transformationType"`
+	TransformationParameters json.RawMessage `json:"transformationParameters"`
+	CreatedAt                string          `json:"createdAt"`
+	Priority                 int             `json:"priority"`
+}
+
+// decodeLegacy decodes the envelope strictly and the row leniently, Decode validates the columns read
+func decodeLegacy(body []byte) (types.ImageProcessing, error) {
+	var envelope legacyEnvelope
+	if err := utils.ParseStrictJSON(body, &envelope); err != nil {
+		return types.ImageProcessing{}, fmt.Errorf("%w: %v", ErrInvalidMessage, err)
+	}
+	var data legacyJob
+	if err := utils.ParseJSON(envelope.Data, &data); err != nil {
+		return types.ImageProcessing{}, fmt.Errorf("%w: data: %v", ErrInvalidMessage, err)
+	}
+	parameters, err := legacyParameters(data.TransformationParameters)
+	if err != nil {
+		return types.ImageProcessing{}, err
+	}
+	return types.ImageProcessing{
+		Id:                       data.Id,
+		UserId:                   data.UserId,
+		FileName:                 data.FileName,
+		S3RawKey:                 data.S3RawKey,
+		Status:                   data.Status,
+		TransformationType:       data.TransformationType,
+		TransformationParameters: parameters,
+		CreatedAt:                data.CreatedAt,
+		Priority:                 data.Priority,
+	}, nil
+}
+
+// legacyParameters unwraps the parameters column. The upload service stores JSON.stringify of the
+// parameters in a Json column, so they arrive as a string holding JSON; a plain object is accepted too.
+func legacyParameters(raw json.RawMessage) (string, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return "", nil
+	}
+	if raw[0] != '"' {
+		return string(raw), nil
+	}
+	var parameters string
+	if err := json.Unmarshal(raw, &parameters); err != nil {
+		return "", fmt.Errorf("%w: transformationParameters: %v", ErrInvalidMessage, err)
+	}
+	return parameters, nil
+}
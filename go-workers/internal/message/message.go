@@ -0,0 +1,123 @@
+// Package message is the wire schema of the job messages the worker consumes. Version 1 is
+//
+//	{"version": 1, "pattern": "resize_queue", "data": {"id": "...", "userId": "...", "fileName": "cat.png",
+//	  "s3RawKey": "raw/cat.png", "transformationType": "RESIZE",
+//	  "transformationParameters": {"width": 640, "height": 480}, "createdAt": "...", "priority": 0}}
+//
+// Messages without a version are the payload the NestJS service emits today, see legacy.go. The same
+// job can be sent Protobuf encoded, see protobuf.go, the AMQP content type tells the two apart.
+package message
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+
+	"github.com/mahirjain10/go-workers/internal/types"
+	"github.com/mahirjain10/go-workers/internal/utils"
+)
+
+// Version is the newest schema version the worker understands
+const Version = 1
+
+// The content types of job and status messages, a message without one is JSON
+const (
+	ContentTypeJSON     = "application/json"
+	ContentTypeProtobuf = "application/x-protobuf"
+)
+
+// ErrInvalidMessage is returned for a body that does not match its schema
+var ErrInvalidMessage = errors.New("invalid job message")
+
+// Envelope is a version 1 job message
+type Envelope struct {
+	Version int    `json:"version"`
+	Pattern string `json:"pattern"`
+	Data    Job    `json:"data"`
+}
+
+// Job is the version 1 job, its parameters are a JSON object rather than a JSON encoded string
+type Job struct {
+	Id                       string          `json:"id"`
+	UserId                   string          `json:"userId"`
+	FileName                 string          `json:"fileName"`
+	S3RawKey                 string          `json:"s3RawKey"`
+	TransformationType       string          `json:"transformationType"`
+	TransformationParameters json.RawMessage `json:"transformationParameters"`
+	CreatedAt                string          `json:"createdAt"`
+	Priority                 int             `json:"priority"`
+}
+
+// Decode decodes and validates a job message of any supported version and encoding. Versioned messages are
+// decoded strictly, the legacy row only for the columns the worker reads. When the body decoded but failed
+// validation the job is returned with the error, so its owner can be told why it failed.
+func Decode(contentType string, body []byte) (types.ImageProcessing, error) {
+	var job types.ImageProcessing
+	var err error
+	switch mediaType(contentType) {
+	case ContentTypeJSON:
+		job, err = decodeJSON(body)
+	case ContentTypeProtobuf:
+		job, err = decodeProtobuf(body)
+	default:
+		return types.ImageProcessing{}, fmt.Errorf("%w: unsupported content type %q", ErrInvalidMessage, contentType)
+	}
+	if err != nil {
+		return types.ImageProcessing{}, err
+	}
+	return job, Validate(job)
+}
+
+// mediaType strips the parameters of contentType, e.g. a charset. Producers that set no content type,
+// like the NestJS ClientProxy, send JSON.
+func mediaType(contentType string) string {
+	if contentType == "" {
+		return ContentTypeJSON
+	}
+	parsed, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return contentType
+	}
+	if parsed == "application/protobuf" {
+		return ContentTypeProtobuf
+	}
+	return parsed
+}
+
+// decodeJSON tells the versions apart, version 1 carries a version field and the legacy payload does not
+func decodeJSON(body []byte) (types.ImageProcessing, error) {
+	var probe struct {
+		Version *int `json:"version"`
+	}
+	if err := json.Unmarshal(body, &probe); err != nil {
+		return types.ImageProcessing{}, fmt.Errorf("%w: %v", ErrInvalidMessage, err)
+	}
+
+	switch {
+	case probe.Version == nil:
+		return decodeLegacy(body)
+	case *probe.Version == 1:
+		return decodeV1(body)
+	default:
+		return types.ImageProcessing{}, fmt.Errorf("%w: unsupported version %d, newest is %d", ErrInvalidMessage, *probe.Version, Version)
+	}
+}
+
+func decodeV1(body []byte) (types.ImageProcessing, error) {
+	var envelope Envelope
+	if err := utils.ParseStrictJSON(body, &envelope); err != nil {
+		return types.ImageProcessing{}, fmt.Errorf("%w: %v", ErrInvalidMessage, err)
+	}
+	data := envelope.Data
+	return types.ImageProcessing{
+		Id:                       data.Id,
+		UserId:                   data.UserId,
+		FileName:                 data.FileName,
+		S3RawKey:                 data.S3RawKey,
+		TransformationType:       data.TransformationType,
+		TransformationParameters: string(data.TransformationParameters),
+		CreatedAt:                data.CreatedAt,
+		Priority:                 data.Priority,
+	}, nil
+}
@@ -0,0 +1,260 @@
+package message
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/mahirjain10/go-workers/internal/transformation"
+	"github.com/mahirjain10/go-workers/internal/types"
+)
+
+// nestPayload is what the NestJS webhook emits for an uploaded image: the ImageProcessing row as Prisma
+// returns it, nullable columns as null and the parameters as the JSON.stringify'd string stored by the API
+const nestPayload = `{"pattern":"resize_queue","data":{
+	"id":"7d0f3f4e-5c1b-4a57-9a44-1f0d2f3c4b5a",
+	"userId":"0b6c2f9e-3d7a-4e21-8f4b-9c1d2e3f4a5b",
+	"filename":"cat.png",
+	"s3RawKey":"raw/0b6c2f9e-cat.png",
+	"publicUrl":null,
+	"status":"PENDING",
+	"transformationType":"RESIZE",
+	"transformationParameters":"{\"width\":640,\"height\":480}",
+	"createdAt":"2025-11-12T16:34:08.123Z",
+	"updatedAt":"2025-11-12T16:34:09.456Z",
+	"errorMessage":null,
+	"priority":0}}`
+
+func TestDecodeJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want types.ImageProcessing
+	}{
+		{
+			name: "NestJS payload",
+			body: nestPayload,
+			want: types.ImageProcessing{
+				Id:                       "7d0f3f4e-5c1b-4a57-9a44-1f0d2f3c4b5a",
+				UserId:                   "0b6c2f9e-3d7a-4e21-8f4b-9c1d2e3f4a5b",
+				FileName:                 "cat.png",
+				S3RawKey:                 "raw/0b6c2f9e-cat.png",
+				Status:                   "PENDING",
+				TransformationType:       "RESIZE",
+				TransformationParameters: `{"width":640,"height":480}`,
+				CreatedAt:                "2025-11-12T16:34:08.123Z",
+			},
+		},
+		{
+			name: "NestJS payload with object parameters and a priority",
+			body: `{"pattern":"rotate_queue","data":{"id":"job-1","userId":"user-1","filename":"cat.png","s3RawKey":"raw/cat.png",
+				"publicUrl":null,"status":"PENDING","transformationType":"ROTATE","transformationParameters":{"degree":90},
+				"createdAt":"2025-11-12T16:34:08.123Z","updatedAt":"2025-11-12T16:34:08.123Z","errorMessage":null,"priority":5}}`,
+			want: types.ImageProcessing{
+				Id:                       "job-1",
+				UserId:                   "user-1",
+				FileName:                 "cat.png",
+				S3RawKey:                 "raw/cat.png",
+				Status:                   "PENDING",
+				TransformationType:       "ROTATE",
+				TransformationParameters: `{"degree":90}`,
+				CreatedAt:                "2025-11-12T16:34:08.123Z",
+				Priority:                 5,
+			},
+		},
+		{
+			name: "NestJS payload with columns the worker does not read",
+			body: `{"pattern":"rotate_queue","data":{"id":"job-1","userId":"user-1","s3RawKey":"raw/cat.png",
+				"transformationType":"ROTATE","transformationParameters":"{\"degree\":90}","tenantId":"t-1",
+				"publicUrl":{"cdn":"https://cdn.example.com/cat.png"},"errorMessage":42}}`,
+			want: types.ImageProcessing{
+				Id:                       "job-1",
+				UserId:                   "user-1",
+				S3RawKey:                 "raw/cat.png",
+				TransformationType:       "ROTATE",
+				TransformationParameters: `{"degree":90}`,
+			},
+		},
+		{
+			name: "version 1",
+			body: `{"version":1,"pattern":"convert_queue","data":{"id":"job-1","userId":"user-1","fileName":"cat.png",
+				"s3RawKey":"raw/cat.png","transformationType":"CONVERT","transformationParameters":{"format":"png"},
+				"createdAt":"2025-11-12T16:34:08Z","priority":2}}`,
+			want: types.ImageProcessing{
+				Id:                       "job-1",
+				UserId:                   "user-1",
+				FileName:                 "cat.png",
+				S3RawKey:                 "raw/cat.png",
+				TransformationType:       "CONVERT",
+				TransformationParameters: `{"format":"png"}`,
+				CreatedAt:                "2025-11-12T16:34:08Z",
+				Priority:                 2,
+			},
+		},
+		{
+			name: "version 1 inspect without parameters",
+			body: `{"version":1,"pattern":"inspect_queue","data":{"id":"job-1","userId":"user-1","s3RawKey":"raw/cat.png",
+				"transformationType":"INSPECT"}}`,
+			want: types.ImageProcessing{
+				Id:                 "job-1",
+				UserId:             "user-1",
+				S3RawKey:           "raw/cat.png",
+				TransformationType: "INSPECT",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Decode(ContentTypeJSON, []byte(tt.body))
+			if err != nil {
+				t.Fatalf("Decode() error = %v", err)
+			}
+			if got != tt.want {
+				t.Fatalf("Decode() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeRejectsInvalidMessages(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{name: "malformed JSON", body: `{"pattern":`},
+		{name: "not an object", body: `[1,2]`},
+		{name: "unknown envelope field", body: `{"pattern":"resize_queue","traceId":"abc","data":{}}`},
+		{name: "legacy data not an object", body: `{"pattern":"resize_queue","data":"job-1"}`},
+		{name: "legacy column read with a wrong type", body: `{"pattern":"rotate_queue","data":{"id":"job-1","userId":"user-1",
+			"s3RawKey":"raw/cat.png","transformationType":"ROTATE","transformationParameters":"{\"degree\":90}","priority":"high"}}`},
+		{name: "legacy column read is missing", body: `{"pattern":"rotate_queue","data":{"userId":"user-1",
+			"s3RawKey":"raw/cat.png","transformationType":"ROTATE","transformationParameters":"{\"degree\":90}"}}`},
+		{name: "unknown version 1 field", body: `{"version":1,"pattern":"rotate_queue","data":{"id":"job-1","userId":"user-1",
+			"s3RawKey":"raw/cat.png","transformationType":"ROTATE","transformationParameters":{"degree":90},"tenantId":"t-1"}}`},
+		{name: "legacy column in version 1", body: `{"version":1,"pattern":"rotate_queue","data":{"id":"job-1","userId":"user-1",
+			"s3RawKey":"raw/cat.png","transformationType":"ROTATE","transformationParameters":{"degree":90},"status":"PENDING"}}`},
+		{name: "unsupported version", body: `{"version":2,"pattern":"rotate_queue","data":{}}`},
+		{name: "trailing data", body: nestPayload + `{}`},
+		{name: "parameters string is not JSON", body: `{"pattern":"rotate_queue","data":{"id":"job-1","userId":"user-1",
+			"s3RawKey":"raw/cat.png","transformationType":"ROTATE","transformationParameters":"{degree"}}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Decode(ContentTypeJSON, []byte(tt.body)); !errors.Is(err, ErrInvalidMessage) && !errors.Is(err, transformation.ErrInvalidParameters) {
+				t.Fatalf("Decode() error = %v, want an invalid message", err)
+			}
+		})
+	}
+}
+
+func TestDecodeReturnsJobThatFailedValidation(t *testing.T) {
+	body := `{"version":1,"pattern":"rotate_queue","data":{"id":"job-1","userId":"user-1","s3RawKey":"raw/cat.png",
+		"transformationType":"ROTATE","transformationParameters":{"degree":45}}}`
+	job, err := Decode(ContentTypeJSON, []byte(body))
+	if !errors.Is(err, transformation.ErrInvalidParameters) {
+		t.Fatalf("Decode() error = %v, want ErrInvalidParameters", err)
+	}
+	// The owner of the job is told why it failed
+	if job.Id != "job-1" || job.UserId != "user-1" {
+		t.Fatalf("Decode() = %+v, want the decoded job", job)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	valid := types.ImageProcessing{
+		Id:                       "job-1",
+		UserId:                   "user-1",
+		S3RawKey:                 "raw/cat.png",
+		TransformationType:       "RESIZE",
+		TransformationParameters: `{"width":640,"height":480}`,
+	}
+	tests := []struct {
+		name    string
+		mutate  func(job *types.ImageProcessing)
+		wantErr error
+	}{
+		{name: "valid", mutate: func(job *types.ImageProcessing) {}},
+		{name: "missing id", mutate: func(job *types.ImageProcessing) { job.Id = "" }, wantErr: ErrInvalidMessage},
+		{name: "missing user", mutate: func(job *types.ImageProcessing) { job.UserId = "" }, wantErr: ErrInvalidMessage},
+		{name: "missing key", mutate: func(job *types.ImageProcessing) { job.S3RawKey = "" }, wantErr: ErrInvalidMessage},
+		{name: "key without prefix", mutate: func(job *types.ImageProcessing) { job.S3RawKey = "cat.png" }, wantErr: ErrInvalidMessage},
+		{name: "missing type", mutate: func(job *types.ImageProcessing) { job.TransformationType = "" }, wantErr: ErrInvalidMessage},
+		{name: "negative priority", mutate: func(job *types.ImageProcessing) { job.Priority = -1 }, wantErr: ErrInvalidMessage},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			job := valid
+			tt.mutate(&job)
+			err := Validate(job)
+			if tt.wantErr == nil && err != nil {
+				t.Fatalf("Validate() = %v, want nil", err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Validate() = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateParameters(t *testing.T) {
+	tests := []struct {
+		transformationType string
+		parameters         string
+		wantErr            error
+	}{
+		{transformationType: "RESIZE", parameters: `{"width":640,"height":480}`},
+		{transformationType: "RESIZE", parameters: `{"width":640}`, wantErr: transformation.ErrInvalidParameters},
+		{transformationType: "RESIZE", parameters: `{"width":0,"height":480}`, wantErr: transformation.ErrInvalidParameters},
+		{transformationType: "RESIZE", parameters: `{"width":640,"height":480,"crop":true}`, wantErr: transformation.ErrInvalidParameters},
+		{transformationType: "FORCE_RESIZE", parameters: `{"width":640,"height":480}`},
+		{transformationType: "FORCE_RESIZE", parameters: `{"width":-1,"height":480}`, wantErr: transformation.ErrInvalidParameters},
+		{transformationType: "ROTATE", parameters: `{"degree":270}`},
+		{transformationType: "ROTATE", parameters: `{"degree":45}`, wantErr: transformation.ErrInvalidParameters},
+		{transformationType: "ROTATE", parameters: ``, wantErr: transformation.ErrInvalidParameters},
+		{transformationType: "CONVERT", parameters: `{"format":"png"}`},
+		{transformationType: "CONVERT", parameters: `{"format":"WEBP"}`, wantErr: transformation.ErrInvalidParameters},
+		{transformationType: "INSPECT", parameters: ``},
+		{transformationType: "INSPECT", parameters: `{"minSharpness":100,"maxClipping":0.1,"minWidth":800,"minHeight":600}`},
+		{transformationType: "INSPECT", parameters: `{"maxClipping":1.5}`, wantErr: transformation.ErrInvalidParameters},
+		{transformationType: "INSPECT", parameters: `{"minWidth":-1}`, wantErr: transformation.ErrInvalidParameters},
+		{transformationType: "INSPECT", parameters: `{"minWidht":800}`, wantErr: transformation.ErrInvalidParameters},
+		{transformationType: "HISTOGRAM", parameters: `{"render":true}`},
+		{transformationType: "HISTOGRAM", parameters: `{"render":"yes"}`, wantErr: transformation.ErrInvalidParameters},
+		{transformationType: "SHARPEN", parameters: `{}`, wantErr: ErrInvalidMessage},
+	}
+	for _, tt := range tests {
+		t.Run(tt.transformationType+" "+tt.parameters, func(t *testing.T) {
+			err := ValidateParameters(tt.transformationType, tt.parameters)
+			if tt.wantErr == nil && err != nil {
+				t.Fatalf("ValidateParameters() = %v, want nil", err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ValidateParameters() = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseInspectKeepsExplicitZeroThresholds(t *testing.T) {
+	defaults := types.Inspect{MinSharpness: 100, MaxClipping: 0.1, MinWidth: 800, MinHeight: 600}
+	tests := []struct {
+		parameters string
+		want       types.Inspect
+	}{
+		{parameters: ``, want: defaults},
+		{parameters: `{}`, want: defaults},
+		{parameters: `{"maxClipping":0}`, want: types.Inspect{MinSharpness: 100, MaxClipping: 0, MinWidth: 800, MinHeight: 600}},
+		{parameters: `{"minSharpness":0,"minWidth":0,"minHeight":0}`, want: types.Inspect{MaxClipping: 0.1}},
+		{parameters: `{"minSharpness":50,"minHeight":1080}`, want: types.Inspect{MinSharpness: 50, MaxClipping: 0.1, MinWidth: 800, MinHeight: 1080}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.parameters, func(t *testing.T) {
+			inspect, err := ParseInspect(tt.parameters)
+			if err != nil {
+				t.Fatalf("ParseInspect() = %v", err)
+			}
+			if got := inspect.Thresholds(defaults); got != tt.want {
+				t.Fatalf("Thresholds() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,263 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.12
+// 	protoc        (unknown)
+// source: imageprocessing/v1/job.proto
+
+package pb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// JobMessage is a job for the workers, the protobuf form of the version 1 JSON message.
+// Publish it with the AMQP content type application/x-protobuf.
+type JobMessage struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Version of the schema, 1
+	Version       int32            `protobuf:"varint,1,opt,name=version,proto3" json:"version,omitempty"`
+	Pattern       string           `protobuf:"bytes,2,opt,name=pattern,proto3" json:"pattern,omitempty"`
+	Data          *ImageProcessing `protobuf:"bytes,3,opt,name=data,proto3" json:"data,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *JobMessage) Reset() {
+	*x = JobMessage{}
+	mi := &file_imageprocessing_v1_job_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *JobMessage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*JobMessage) ProtoMessage() {}
+
+func (x *JobMessage) ProtoReflect() protoreflect.Message {
+	mi := &file_imageprocessing_v1_job_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use JobMessage.ProtoReflect.Descriptor instead.
+func (*JobMessage) Descriptor() ([]byte, []int) {
+	return file_imageprocessing_v1_job_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *JobMessage) GetVersion() int32 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+func (x *JobMessage) GetPattern() string {
+	if x != nil {
+		return x.Pattern
+	}
+	return ""
+}
+
+func (x *JobMessage) GetData() *ImageProcessing {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+// ImageProcessing is one job on one uploaded image
+type ImageProcessing struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	Id       string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	UserId   string                 `protobuf:"bytes,2,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	FileName string                 `protobuf:"bytes,3,opt,name=file_name,json=fileName,proto3" json:"file_name,omitempty"`
+	// Key of the uploaded image, e.g. raw/cat.png
+	S3RawKey string `protobuf:"bytes,4,opt,name=s3_raw_key,json=s3RawKey,proto3" json:"s3_raw_key,omitempty"`
+	// RESIZE, FORCE_RESIZE, ROTATE, CONVERT, INSPECT or HISTOGRAM
+	TransformationType string `protobuf:"bytes,5,opt,name=transformation_type,json=transformationType,proto3" json:"transformation_type,omitempty"`
+	// JSON object with the parameters of the transformation type, e.g. {"width":640,"height":480}.
+	// Kept as JSON so a new transformation needs no schema change.
+	TransformationParameters string `protobuf:"bytes,6,opt,name=transformation_parameters,json=transformationParameters,proto3" json:"transformation_parameters,omitempty"`
+	// RFC 3339 creation time of the job
+	CreatedAt     string `protobuf:"bytes,7,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	Priority      int32  `protobuf:"varint,8,opt,name=priority,proto3" json:"priority,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ImageProcessing) Reset() {
+	*x = ImageProcessing{}
+	mi := &file_imageprocessing_v1_job_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ImageProcessing) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ImageProcessing) ProtoMessage() {}
+
+func (x *ImageProcessing) ProtoReflect() protoreflect.Message {
+	mi := &file_imageprocessing_v1_job_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ImageProcessing.ProtoReflect.Descriptor instead.
+func (*ImageProcessing) Descriptor() ([]byte, []int) {
+	return file_imageprocessing_v1_job_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *ImageProcessing) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *ImageProcessing) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *ImageProcessing) GetFileName() string {
+	if x != nil {
+		return x.FileName
+	}
+	return ""
+}
+
+func (x *ImageProcessing) GetS3RawKey() string {
+	if x != nil {
+		return x.S3RawKey
+	}
+	return ""
+}
+
+func (x *ImageProcessing) GetTransformationType() string {
+	if x != nil {
+		return x.TransformationType
+	}
+	return ""
+}
+
+func (x *ImageProcessing) GetTransformationParameters() string {
+	if x != nil {
+		return x.TransformationParameters
+	}
+	return ""
+}
+
+func (x *ImageProcessing) GetCreatedAt() string {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return ""
+}
+
+func (x *ImageProcessing) GetPriority() int32 {
+	if x != nil {
+		return x.Priority
+	}
+	return 0
+}
+
+var File_imageprocessing_v1_job_proto protoreflect.FileDescriptor
+
+const file_imageprocessing_v1_job_proto_rawDesc = "" +
+	"\n" +
+	"\x1cimageprocessing/v1/job.proto\x12\x12imageprocessing.v1\"y\n" +
+	"\n" +
+	"JobMessage\x12\x18\n" +
+	"\aversion\x18\x01 \x01(\x05R\aversion\x12\x18\n" +
+	"\apattern\x18\x02 \x01(\tR\apattern\x127\n" +
+	"\x04data\x18\x03 \x01(\v2#.imageprocessing.v1.ImageProcessingR\x04data\"\x9e\x02\n" +
+	"\x0fImageProcessing\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x17\n" +
+	"\auser_id\x18\x02 \x01(\tR\x06userId\x12\x1b\n" +
+	"\tfile_name\x18\x03 \x01(\tR\bfileName\x12\x1c\n" +
+	"\n" +
+	"s3_raw_key\x18\x04 \x01(\tR\bs3RawKey\x12/\n" +
+	"\x13transformation_type\x18\x05 \x01(\tR\x12transformationType\x12;\n" +
+	"\x19transformation_parameters\x18\x06 \x01(\tR\x18transformationParameters\x12\x1d\n" +
+	"\n" +
+	"created_at\x18\a \x01(\tR\tcreatedAt\x12\x1a\n" +
+	"\bpriority\x18\b \x01(\x05R\bpriorityB:Z8github.com/mahirjain10/go-workers/internal/message/pb;pbb\x06proto3"
+
+var (
+	file_imageprocessing_v1_job_proto_rawDescOnce sync.Once
+	file_imageprocessing_v1_job_proto_rawDescData []byte
+)
+
+func file_imageprocessing_v1_job_proto_rawDescGZIP() []byte {
+	file_imageprocessing_v1_job_proto_rawDescOnce.Do(func() {
+		file_imageprocessing_v1_job_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_imageprocessing_v1_job_proto_rawDesc), len(file_imageprocessing_v1_job_proto_rawDesc)))
+	})
+	return file_imageprocessing_v1_job_proto_rawDescData
+}
+
+var file_imageprocessing_v1_job_proto_msgTypes = make([]protoimpl.MessageInfo, 2)
+var file_imageprocessing_v1_job_proto_goTypes = []any{
+	(*JobMessage)(nil),      // 0: imageprocessing.v1.JobMessage
+	(*ImageProcessing)(nil), // 1: imageprocessing.v1.ImageProcessing
+}
+var file_imageprocessing_v1_job_proto_depIdxs = []int32{
+	1, // 0: imageprocessing.v1.JobMessage.data:type_name -> imageprocessing.v1.ImageProcessing
+	1, // [1:1] is the sub-list for method output_type
+	1, // [1:1] is the sub-list for method input_type
+	1, // [1:1] is the sub-list for extension type_name
+	1, // [1:1] is the sub-list for extension extendee
+	0, // [0:1] is the sub-list for field type_name
+}
+
+func init() { file_imageprocessing_v1_job_proto_init() }
+func file_imageprocessing_v1_job_proto_init() {
+	if File_imageprocessing_v1_job_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_imageprocessing_v1_job_proto_rawDesc), len(file_imageprocessing_v1_job_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   2,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_imageprocessing_v1_job_proto_goTypes,
+		DependencyIndexes: file_imageprocessing_v1_job_proto_depIdxs,
+		MessageInfos:      file_imageprocessing_v1_job_proto_msgTypes,
+	}.Build()
+	File_imageprocessing_v1_job_proto = out.File
+	file_imageprocessing_v1_job_proto_goTypes = nil
+	file_imageprocessing_v1_job_proto_depIdxs = nil
+}
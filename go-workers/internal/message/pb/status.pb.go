@@ -0,0 +1,538 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.12
+// 	protoc        (unknown)
+// source: imageprocessing/v1/status.proto
+
+package pb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// StatusMessage is a job status published by the workers
+type StatusMessage struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Pattern       string                 `protobuf:"bytes,1,opt,name=pattern,proto3" json:"pattern,omitempty"`
+	Data          *StatusData            `protobuf:"bytes,2,opt,name=data,proto3" json:"data,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StatusMessage) Reset() {
+	*x = StatusMessage{}
+	mi := &file_imageprocessing_v1_status_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StatusMessage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StatusMessage) ProtoMessage() {}
+
+func (x *StatusMessage) ProtoReflect() protoreflect.Message {
+	mi := &file_imageprocessing_v1_status_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StatusMessage.ProtoReflect.Descriptor instead.
+func (*StatusMessage) Descriptor() ([]byte, []int) {
+	return file_imageprocessing_v1_status_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *StatusMessage) GetPattern() string {
+	if x != nil {
+		return x.Pattern
+	}
+	return ""
+}
+
+func (x *StatusMessage) GetData() *StatusData {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+type StatusData struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	Id     string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	UserId string                 `protobuf:"bytes,2,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	// PROCESSING, PROCESSED, FAILED, CANCELLED or EXPIRED
+	Status    string `protobuf:"bytes,3,opt,name=status,proto3" json:"status,omitempty"`
+	PublicUrl string `protobuf:"bytes,4,opt,name=public_url,json=publicUrl,proto3" json:"public_url,omitempty"`
+	ErrorMsg  string `protobuf:"bytes,5,opt,name=error_msg,json=errorMsg,proto3" json:"error_msg,omitempty"`
+	// Stable code of a FAILED status, e.g. INVALID_PARAMETERS
+	ErrorCode string `protobuf:"bytes,6,opt,name=error_code,json=errorCode,proto3" json:"error_code,omitempty"`
+	// Placeholders of a PROCESSED image, when placeholder generation is enabled
+	BlurHash string `protobuf:"bytes,7,opt,name=blur_hash,json=blurHash,proto3" json:"blur_hash,omitempty"`
+	Lqip     string `protobuf:"bytes,8,opt,name=lqip,proto3" json:"lqip,omitempty"`
+	// Only set for INSPECT jobs
+	Inspection *InspectionResult `protobuf:"bytes,9,opt,name=inspection,proto3" json:"inspection,omitempty"`
+	// Only set for HISTOGRAM jobs
+	Histogram     *HistogramResult `protobuf:"bytes,10,opt,name=histogram,proto3" json:"histogram,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StatusData) Reset() {
+	*x = StatusData{}
+	mi := &file_imageprocessing_v1_status_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StatusData) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StatusData) ProtoMessage() {}
+
+func (x *StatusData) ProtoReflect() protoreflect.Message {
+	mi := &file_imageprocessing_v1_status_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StatusData.ProtoReflect.Descriptor instead.
+func (*StatusData) Descriptor() ([]byte, []int) {
+	return file_imageprocessing_v1_status_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *StatusData) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *StatusData) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *StatusData) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *StatusData) GetPublicUrl() string {
+	if x != nil {
+		return x.PublicUrl
+	}
+	return ""
+}
+
+func (x *StatusData) GetErrorMsg() string {
+	if x != nil {
+		return x.ErrorMsg
+	}
+	return ""
+}
+
+func (x *StatusData) GetErrorCode() string {
+	if x != nil {
+		return x.ErrorCode
+	}
+	return ""
+}
+
+func (x *StatusData) GetBlurHash() string {
+	if x != nil {
+		return x.BlurHash
+	}
+	return ""
+}
+
+func (x *StatusData) GetLqip() string {
+	if x != nil {
+		return x.Lqip
+	}
+	return ""
+}
+
+func (x *StatusData) GetInspection() *InspectionResult {
+	if x != nil {
+		return x.Inspection
+	}
+	return nil
+}
+
+func (x *StatusData) GetHistogram() *HistogramResult {
+	if x != nil {
+		return x.Histogram
+	}
+	return nil
+}
+
+type InspectionResult struct {
+	state             protoimpl.MessageState `protogen:"open.v1"`
+	Passed            bool                   `protobuf:"varint,1,opt,name=passed,proto3" json:"passed,omitempty"`
+	Sharpness         float64                `protobuf:"fixed64,2,opt,name=sharpness,proto3" json:"sharpness,omitempty"`
+	ShadowClipping    float64                `protobuf:"fixed64,3,opt,name=shadow_clipping,json=shadowClipping,proto3" json:"shadow_clipping,omitempty"`
+	HighlightClipping float64                `protobuf:"fixed64,4,opt,name=highlight_clipping,json=highlightClipping,proto3" json:"highlight_clipping,omitempty"`
+	Width             int32                  `protobuf:"varint,5,opt,name=width,proto3" json:"width,omitempty"`
+	Height            int32                  `protobuf:"varint,6,opt,name=height,proto3" json:"height,omitempty"`
+	// Every failed check
+	Reasons       []string `protobuf:"bytes,7,rep,name=reasons,proto3" json:"reasons,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *InspectionResult) Reset() {
+	*x = InspectionResult{}
+	mi := &file_imageprocessing_v1_status_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *InspectionResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*InspectionResult) ProtoMessage() {}
+
+func (x *InspectionResult) ProtoReflect() protoreflect.Message {
+	mi := &file_imageprocessing_v1_status_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use InspectionResult.ProtoReflect.Descriptor instead.
+func (*InspectionResult) Descriptor() ([]byte, []int) {
+	return file_imageprocessing_v1_status_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *InspectionResult) GetPassed() bool {
+	if x != nil {
+		return x.Passed
+	}
+	return false
+}
+
+func (x *InspectionResult) GetSharpness() float64 {
+	if x != nil {
+		return x.Sharpness
+	}
+	return 0
+}
+
+func (x *InspectionResult) GetShadowClipping() float64 {
+	if x != nil {
+		return x.ShadowClipping
+	}
+	return 0
+}
+
+func (x *InspectionResult) GetHighlightClipping() float64 {
+	if x != nil {
+		return x.HighlightClipping
+	}
+	return 0
+}
+
+func (x *InspectionResult) GetWidth() int32 {
+	if x != nil {
+		return x.Width
+	}
+	return 0
+}
+
+func (x *InspectionResult) GetHeight() int32 {
+	if x != nil {
+		return x.Height
+	}
+	return 0
+}
+
+func (x *InspectionResult) GetReasons() []string {
+	if x != nil {
+		return x.Reasons
+	}
+	return nil
+}
+
+// ChannelStats is the 256-bin histogram and the summary statistics of one channel
+type ChannelStats struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Bins          []int32                `protobuf:"varint,1,rep,packed,name=bins,proto3" json:"bins,omitempty"`
+	Min           int32                  `protobuf:"varint,2,opt,name=min,proto3" json:"min,omitempty"`
+	Max           int32                  `protobuf:"varint,3,opt,name=max,proto3" json:"max,omitempty"`
+	Mean          float64                `protobuf:"fixed64,4,opt,name=mean,proto3" json:"mean,omitempty"`
+	StdDev        float64                `protobuf:"fixed64,5,opt,name=std_dev,json=stdDev,proto3" json:"std_dev,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ChannelStats) Reset() {
+	*x = ChannelStats{}
+	mi := &file_imageprocessing_v1_status_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ChannelStats) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ChannelStats) ProtoMessage() {}
+
+func (x *ChannelStats) ProtoReflect() protoreflect.Message {
+	mi := &file_imageprocessing_v1_status_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ChannelStats.ProtoReflect.Descriptor instead.
+func (*ChannelStats) Descriptor() ([]byte, []int) {
+	return file_imageprocessing_v1_status_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *ChannelStats) GetBins() []int32 {
+	if x != nil {
+		return x.Bins
+	}
+	return nil
+}
+
+func (x *ChannelStats) GetMin() int32 {
+	if x != nil {
+		return x.Min
+	}
+	return 0
+}
+
+func (x *ChannelStats) GetMax() int32 {
+	if x != nil {
+		return x.Max
+	}
+	return 0
+}
+
+func (x *ChannelStats) GetMean() float64 {
+	if x != nil {
+		return x.Mean
+	}
+	return 0
+}
+
+func (x *ChannelStats) GetStdDev() float64 {
+	if x != nil {
+		return x.StdDev
+	}
+	return 0
+}
+
+type HistogramResult struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Red           *ChannelStats          `protobuf:"bytes,1,opt,name=red,proto3" json:"red,omitempty"`
+	Green         *ChannelStats          `protobuf:"bytes,2,opt,name=green,proto3" json:"green,omitempty"`
+	Blue          *ChannelStats          `protobuf:"bytes,3,opt,name=blue,proto3" json:"blue,omitempty"`
+	Luminance     *ChannelStats          `protobuf:"bytes,4,opt,name=luminance,proto3" json:"luminance,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *HistogramResult) Reset() {
+	*x = HistogramResult{}
+	mi := &file_imageprocessing_v1_status_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HistogramResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HistogramResult) ProtoMessage() {}
+
+func (x *HistogramResult) ProtoReflect() protoreflect.Message {
+	mi := &file_imageprocessing_v1_status_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HistogramResult.ProtoReflect.Descriptor instead.
+func (*HistogramResult) Descriptor() ([]byte, []int) {
+	return file_imageprocessing_v1_status_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *HistogramResult) GetRed() *ChannelStats {
+	if x != nil {
+		return x.Red
+	}
+	return nil
+}
+
+func (x *HistogramResult) GetGreen() *ChannelStats {
+	if x != nil {
+		return x.Green
+	}
+	return nil
+}
+
+func (x *HistogramResult) GetBlue() *ChannelStats {
+	if x != nil {
+		return x.Blue
+	}
+	return nil
+}
+
+func (x *HistogramResult) GetLuminance() *ChannelStats {
+	if x != nil {
+		return x.Luminance
+	}
+	return nil
+}
+
+var File_imageprocessing_v1_status_proto protoreflect.FileDescriptor
+
+const file_imageprocessing_v1_status_proto_rawDesc = "" +
+	"\n" +
+	"\x1fimageprocessing/v1/status.proto\x12\x12imageprocessing.v1\"]\n" +
+	"\rStatusMessage\x12\x18\n" +
+	"\apattern\x18\x01 \x01(\tR\apattern\x122\n" +
+	"\x04data\x18\x02 \x01(\v2\x1e.imageprocessing.v1.StatusDataR\x04data\"\xe2\x02\n" +
+	"\n" +
+	"StatusData\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x17\n" +
+	"\auser_id\x18\x02 \x01(\tR\x06userId\x12\x16\n" +
+	"\x06status\x18\x03 \x01(\tR\x06status\x12\x1d\n" +
+	"\n" +
+	"public_url\x18\x04 \x01(\tR\tpublicUrl\x12\x1b\n" +
+	"\terror_msg\x18\x05 \x01(\tR\berrorMsg\x12\x1d\n" +
+	"\n" +
+	"error_code\x18\x06 \x01(\tR\terrorCode\x12\x1b\n" +
+	"\tblur_hash\x18\a \x01(\tR\bblurHash\x12\x12\n" +
+	"\x04lqip\x18\b \x01(\tR\x04lqip\x12D\n" +
+	"\n" +
+	"inspection\x18\t \x01(\v2$.imageprocessing.v1.InspectionResultR\n" +
+	"inspection\x12A\n" +
+	"\thistogram\x18\n" +
+	" \x01(\v2#.imageprocessing.v1.HistogramResultR\thistogram\"\xe8\x01\n" +
+	"\x10InspectionResult\x12\x16\n" +
+	"\x06passed\x18\x01 \x01(\bR\x06passed\x12\x1c\n" +
+	"\tsharpness\x18\x02 \x01(\x01R\tsharpness\x12'\n" +
+	"\x0fshadow_clipping\x18\x03 \x01(\x01R\x0eshadowClipping\x12-\n" +
+	"\x12highlight_clipping\x18\x04 \x01(\x01R\x11highlightClipping\x12\x14\n" +
+	"\x05width\x18\x05 \x01(\x05R\x05width\x12\x16\n" +
+	"\x06height\x18\x06 \x01(\x05R\x06height\x12\x18\n" +
+	"\areasons\x18\a \x03(\tR\areasons\"s\n" +
+	"\fChannelStats\x12\x12\n" +
+	"\x04bins\x18\x01 \x03(\x05R\x04bins\x12\x10\n" +
+	"\x03min\x18\x02 \x01(\x05R\x03min\x12\x10\n" +
+	"\x03max\x18\x03 \x01(\x05R\x03max\x12\x12\n" +
+	"\x04mean\x18\x04 \x01(\x01R\x04mean\x12\x17\n" +
+	"\astd_dev\x18\x05 \x01(\x01R\x06stdDev\"\xf3\x01\n" +
+	"\x0fHistogramResult\x122\n" +
+	"\x03red\x18\x01 \x01(\v2 .imageprocessing.v1.ChannelStatsR\x03red\x126\n" +
+	"\x05green\x18\x02 \x01(\v2 .imageprocessing.v1.ChannelStatsR\x05green\x124\n" +
+	"\x04blue\x18\x03 \x01(\v2 .imageprocessing.v1.ChannelStatsR\x04blue\x12>\n" +
+	"\tluminance\x18\x04 \x01(\v2 .imageprocessing.v1.ChannelStatsR\tluminanceB:Z8github.com/mahirjain10/go-workers/internal/message/pb;pbb\x06proto3"
+
+var (
+	file_imageprocessing_v1_status_proto_rawDescOnce sync.Once
+	file_imageprocessing_v1_status_proto_rawDescData []byte
+)
+
+func file_imageprocessing_v1_status_proto_rawDescGZIP() []byte {
+	file_imageprocessing_v1_status_proto_rawDescOnce.Do(func() {
+		file_imageprocessing_v1_status_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_imageprocessing_v1_status_proto_rawDesc), len(file_imageprocessing_v1_status_proto_rawDesc)))
+	})
+	return file_imageprocessing_v1_status_proto_rawDescData
+}
+
+var file_imageprocessing_v1_status_proto_msgTypes = make([]protoimpl.MessageInfo, 5)
+var file_imageprocessing_v1_status_proto_goTypes = []any{
+	(*StatusMessage)(nil),    // 0: imageprocessing.v1.StatusMessage
+	(*StatusData)(nil),       // 1: imageprocessing.v1.StatusData
+	(*InspectionResult)(nil), // 2: imageprocessing.v1.InspectionResult
+	(*ChannelStats)(nil),     // 3: imageprocessing.v1.ChannelStats
+	(*HistogramResult)(nil),  // 4: imageprocessing.v1.HistogramResult
+}
+var file_imageprocessing_v1_status_proto_depIdxs = []int32{
+	1, // 0: imageprocessing.v1.StatusMessage.data:type_name -> imageprocessing.v1.StatusData
+	2, // 1: imageprocessing.v1.StatusData.inspection:type_name -> imageprocessing.v1.InspectionResult
+	4, // 2: imageprocessing.v1.StatusData.histogram:type_name -> imageprocessing.v1.HistogramResult
+	3, // 3: imageprocessing.v1.HistogramResult.red:type_name -> imageprocessing.v1.ChannelStats
+	3, // 4: imageprocessing.v1.HistogramResult.green:type_name -> imageprocessing.v1.ChannelStats
+	3, // 5: imageprocessing.v1.HistogramResult.blue:type_name -> imageprocessing.v1.ChannelStats
+	3, // 6: imageprocessing.v1.HistogramResult.luminance:type_name -> imageprocessing.v1.ChannelStats
+	7, // [7:7] is the sub-list for method output_type
+	7, // [7:7] is the sub-list for method input_type
+	7, // [7:7] is the sub-list for extension type_name
+	7, // [7:7] is the sub-list for extension extendee
+	0, // [0:7] is the sub-list for field type_name
+}
+
+func init() { file_imageprocessing_v1_status_proto_init() }
+func file_imageprocessing_v1_status_proto_init() {
+	if File_imageprocessing_v1_status_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_imageprocessing_v1_status_proto_rawDesc), len(file_imageprocessing_v1_status_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   5,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_imageprocessing_v1_status_proto_goTypes,
+		DependencyIndexes: file_imageprocessing_v1_status_proto_depIdxs,
+		MessageInfos:      file_imageprocessing_v1_status_proto_msgTypes,
+	}.Build()
+	File_imageprocessing_v1_status_proto = out.File
+	file_imageprocessing_v1_status_proto_goTypes = nil
+	file_imageprocessing_v1_status_proto_depIdxs = nil
+}
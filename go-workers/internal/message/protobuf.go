@@ -0,0 +1,114 @@
+package message
+
+import (
+	"fmt"
+
+	"github.com/mahirjain10/go-workers/internal/message/pb"
+	"github.com/mahirjain10/go-workers/internal/types"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// The Go code in pb is generated from the shared definitions in proto/ at the repository root
+//go:generate protoc --proto_path=../../../proto --go_out=../.. --go_opt=module=github.com/mahirjain10/go-workers imageprocessing/v1/job.proto imageprocessing/v1/status.proto
+
+// decodeProtobuf decodes a pb.JobMessage. Like the JSON schema it rejects fields it does not know, which
+// Protobuf would otherwise keep silently.
+func decodeProtobuf(body []byte) (types.ImageProcessing, error) {
+	var message pb.JobMessage
+	if err := proto.Unmarshal(body, &message); err != nil {
+		return types.ImageProcessing{}, fmt.Errorf("%w: %v", ErrInvalidMessage, err)
+	}
+	if hasUnknownFields(message.ProtoReflect()) {
+		return types.ImageProcessing{}, fmt.Errorf("%w: message has unknown fields", ErrInvalidMessage)
+	}
+	if message.GetVersion() != Version {
+		return types.ImageProcessing{}, fmt.Errorf("%w: unsupported version %d, newest is %d", ErrInvalidMessage, message.GetVersion(), Version)
+	}
+	data := message.GetData()
+	return types.ImageProcessing{
+		Id:                       data.GetId(),
+		UserId:                   data.GetUserId(),
+		FileName:                 data.GetFileName(),
+		S3RawKey:                 data.GetS3RawKey(),
+		TransformationType:       data.GetTransformationType(),
+		TransformationParameters: data.GetTransformationParameters(),
+		CreatedAt:                data.GetCreatedAt(),
+		Priority:                 int(data.GetPriority()),
+	}, nil
+}
+
+// hasUnknownFields reports unknown fields in m or any message it holds
+func hasUnknownFields(m protoreflect.Message) bool {
+	if len(m.GetUnknown()) > 0 {
+		return true
+	}
+	unknown := false
+	m.Range(func(field protoreflect.FieldDescriptor, value protoreflect.Value) bool {
+		if field.Kind() != protoreflect.MessageKind {
+			return true
+		}
+		if field.IsList() {
+			list := value.List()
+			for i := 0; i < list.Len() && !unknown; i++ {
+				unknown = hasUnknownFields(list.Get(i).Message())
+			}
+		} else {
+			unknown = hasUnknownFields(value.Message())
+		}
+		return !unknown
+	})
+	return unknown
+}
+
+// statusToProtobuf converts a status message to its pb form
+func statusToProtobuf(status types.StatusMessage) *pb.StatusMessage {
+	data := status.Data
+	message := &pb.StatusMessage{
+		Pattern: status.Pattern,
+		Data: &pb.StatusData{
+			Id:        data.ID,
+			UserId:    data.UserID,
+			Status:    data.Status,
+			PublicUrl: data.PublicURL,
+			ErrorMsg:  data.ErrorMsg,
+			ErrorCode: data.ErrorCode,
+			BlurHash:  data.BlurHash,
+			Lqip:      data.Lqip,
+		},
+	}
+	if inspection := data.Inspection; inspection != nil {
+		message.Data.Inspection = &pb.InspectionResult{
+			Passed:            inspection.Passed,
+			Sharpness:         inspection.Sharpness,
+			ShadowClipping:    inspection.ShadowClipping,
+			HighlightClipping: inspection.HighlightClipping,
+			Width:             int32(inspection.Width),
+			Height:            int32(inspection.Height),
+			Reasons:           inspection.Reasons,
+		}
+	}
+	if histogram := data.Histogram; histogram != nil {
+		message.Data.Histogram = &pb.HistogramResult{
+			Red:       channelToProtobuf(histogram.Red),
+			Green:     channelToProtobuf(histogram.Green),
+			Blue:      channelToProtobuf(histogram.Blue),
+			Luminance: channelToProtobuf(histogram.Luminance),
+		}
+	}
+	return message
+}
+
+func channelToProtobuf(stats types.ChannelStats) *pb.ChannelStats {
+	bins := make([]int32, len(stats.Bins))
+	for i, count := range stats.Bins {
+		bins[i] = int32(count)
+	}
+	return &pb.ChannelStats{
+		Bins:   bins,
+		Min:    int32(stats.Min),
+		Max:    int32(stats.Max),
+		Mean:   stats.Mean,
+		StdDev: stats.StdDev,
+	}
+}
@@ -0,0 +1,214 @@
+package message
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/mahirjain10/go-workers/internal/message/pb"
+	"github.com/mahirjain10/go-workers/internal/types"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+)
+
+func testJobMessage() *pb.JobMessage {
+	return &pb.JobMessage{
+		Version: Version,
+		Pattern: "resize_queue",
+		Data: &pb.ImageProcessing{
+			Id:                       "job-1",
+			UserId:                   "user-1",
+			FileName:                 "cat.png",
+			S3RawKey:                 "raw/cat.png",
+			TransformationType:       "RESIZE",
+			TransformationParameters: `{"width":640,"height":480}`,
+			CreatedAt:                "2025-11-12T16:34:08Z",
+			Priority:                 3,
+		},
+	}
+}
+
+func mustMarshal(t *testing.T, message proto.Message) []byte {
+	t.Helper()
+	body, err := proto.Marshal(message)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return body
+}
+
+func TestDecodeDispatchesOnContentType(t *testing.T) {
+	protobufBody := mustMarshal(t, testJobMessage())
+	jsonBody := []byte(`{"version":1,"pattern":"resize_queue","data":{"id":"job-1","userId":"user-1","fileName":"cat.png",
+		"s3RawKey":"raw/cat.png","transformationType":"RESIZE","transformationParameters":{"width":640,"height":480},
+		"createdAt":"2025-11-12T16:34:08Z","priority":3}}`)
+	want := types.ImageProcessing{
+		Id:                       "job-1",
+		UserId:                   "user-1",
+		FileName:                 "cat.png",
+		S3RawKey:                 "raw/cat.png",
+		TransformationType:       "RESIZE",
+		TransformationParameters: `{"width":640,"height":480}`,
+		CreatedAt:                "2025-11-12T16:34:08Z",
+		Priority:                 3,
+	}
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        []byte
+		wantErr     bool
+	}{
+		{name: "protobuf", contentType: "application/x-protobuf", body: protobufBody},
+		{name: "protobuf alias", contentType: "application/protobuf", body: protobufBody},
+		{name: "protobuf with parameters", contentType: `application/x-protobuf; proto="imageprocessing.v1.JobMessage"`, body: protobufBody},
+		{name: "json", contentType: "application/json", body: jsonBody},
+		{name: "json with charset", contentType: "application/json; charset=utf-8", body: jsonBody},
+		{name: "no content type is json", contentType: "", body: jsonBody},
+		{name: "protobuf body sent as json", contentType: "application/json", body: protobufBody, wantErr: true},
+		{name: "json body sent as protobuf", contentType: "application/x-protobuf", body: jsonBody, wantErr: true},
+		{name: "unsupported content type", contentType: "text/plain", body: jsonBody, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Decode(tt.contentType, tt.body)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidMessage) {
+					t.Fatalf("Decode() error = %v, want ErrInvalidMessage", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Decode() error = %v", err)
+			}
+			if got != want {
+				t.Fatalf("Decode() = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestDecodeProtobufRejectsInvalidMessages(t *testing.T) {
+	// unknownField appends a field number the schema does not define
+	unknownField := func(body []byte) []byte {
+		body = protowire.AppendTag(body, 99, protowire.VarintType)
+		return protowire.AppendVarint(body, 1)
+	}
+	nested := testJobMessage()
+	nested.Data.ProtoReflect().SetUnknown(unknownField(nil))
+	wrongVersion := testJobMessage()
+	wrongVersion.Version = 2
+	noVersion := testJobMessage()
+	noVersion.Version = 0
+
+	tests := []struct {
+		name string
+		body []byte
+	}{
+		{name: "unknown top level field", body: unknownField(mustMarshal(t, testJobMessage()))},
+		{name: "unknown field in the job", body: mustMarshal(t, nested)},
+		{name: "unsupported version", body: mustMarshal(t, wrongVersion)},
+		{name: "missing version", body: mustMarshal(t, noVersion)},
+		{name: "truncated", body: mustMarshal(t, testJobMessage())[:10]},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Decode(ContentTypeProtobuf, tt.body); !errors.Is(err, ErrInvalidMessage) {
+				t.Fatalf("Decode() error = %v, want ErrInvalidMessage", err)
+			}
+		})
+	}
+}
+
+func testStatus() types.StatusMessage {
+	bins := make([]int, 256)
+	bins[0], bins[255] = 10, 20
+	stats := types.ChannelStats{Bins: bins, Min: 0, Max: 255, Mean: 170, StdDev: 120.5}
+	return types.StatusMessage{
+		Pattern: "status_queue",
+		Data: types.StatusData{
+			ID:        "job-1",
+			UserID:    "user-1",
+			Status:    types.PROCCESSED,
+			PublicURL: "https://cdn.example.com/processed/cat.png",
+			BlurHash:  "LEHV6nWB2yk8pyo0adR*.7kCMdnj",
+			Lqip:      "data:image/jpeg;base64,AAAA",
+			Inspection: &types.InspectionResult{
+				Passed:            false,
+				Sharpness:         42.5,
+				ShadowClipping:    0.01,
+				HighlightClipping: 0.2,
+				Width:             640,
+				Height:            480,
+				Reasons:           []string{"too blurry", "highlights clipped"},
+			},
+			Histogram: &types.HistogramResult{Red: stats, Green: stats, Blue: stats, Luminance: stats},
+		},
+	}
+}
+
+func TestEncodeStatusProtobufRoundTrip(t *testing.T) {
+	tests := []struct {
+		name   string
+		status types.StatusMessage
+	}{
+		{name: "processed with results", status: testStatus()},
+		{name: "failed", status: types.StatusMessage{Pattern: "status_queue", Data: types.StatusData{
+			ID: "job-1", UserID: "user-1", Status: types.FAILED, ErrorMsg: "image could not be decoded", ErrorCode: "DECODE_FAILED",
+		}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, err := EncodeStatus("application/x-protobuf", tt.status)
+			if err != nil {
+				t.Fatalf("EncodeStatus() error = %v", err)
+			}
+			var decoded pb.StatusMessage
+			if err := proto.Unmarshal(body, &decoded); err != nil {
+				t.Fatalf("proto.Unmarshal() error = %v", err)
+			}
+			if want := statusToProtobuf(tt.status); !proto.Equal(&decoded, want) {
+				t.Fatalf("decoded status = %v, want %v", &decoded, want)
+			}
+
+			data := decoded.GetData()
+			if data.GetId() != tt.status.Data.ID || data.GetStatus() != tt.status.Data.Status || data.GetErrorCode() != tt.status.Data.ErrorCode {
+				t.Fatalf("decoded status data = %v, want %+v", data, tt.status.Data)
+			}
+			if inspection := tt.status.Data.Inspection; inspection != nil {
+				got := data.GetInspection()
+				if got.GetSharpness() != inspection.Sharpness || int(got.GetWidth()) != inspection.Width || !reflect.DeepEqual(got.GetReasons(), inspection.Reasons) {
+					t.Fatalf("decoded inspection = %v, want %+v", got, inspection)
+				}
+			}
+			if histogram := tt.status.Data.Histogram; histogram != nil {
+				bins := data.GetHistogram().GetRed().GetBins()
+				if len(bins) != 256 || int(bins[255]) != histogram.Red.Bins[255] {
+					t.Fatalf("decoded red bins = %v, want %v", bins, histogram.Red.Bins)
+				}
+			}
+		})
+	}
+}
+
+func TestEncodeStatusJSONRoundTrip(t *testing.T) {
+	status := testStatus()
+	body, err := EncodeStatus("application/json; charset=utf-8", status)
+	if err != nil {
+		t.Fatalf("EncodeStatus() error = %v", err)
+	}
+	var decoded types.StatusMessage
+	if err := json.Unmarshal(body, &decoded); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	if !reflect.DeepEqual(decoded, status) {
+		t.Fatalf("decoded status = %+v, want %+v", decoded, status)
+	}
+}
+
+func TestEncodeStatusUnsupportedContentType(t *testing.T) {
+	if _, err := EncodeStatus("text/plain", testStatus()); err == nil {
+		t.Fatal("EncodeStatus(text/plain) succeeded, want an error")
+	}
+}
@@ -0,0 +1,21 @@
+package message
+
+import (
+	"fmt"
+
+	"github.com/mahirjain10/go-workers/internal/types"
+	"github.com/mahirjain10/go-workers/internal/utils"
+	"google.golang.org/protobuf/proto"
+)
+
+// EncodeStatus serializes a status message in the given content type, JSON or Protobuf
+func EncodeStatus(contentType string, status types.StatusMessage) ([]byte, error) {
+	switch mediaType(contentType) {
+	case ContentTypeJSON:
+		return utils.SerializeJSON(status)
+	case ContentTypeProtobuf:
+		return proto.Marshal(statusToProtobuf(status))
+	default:
+		return nil, fmt.Errorf("unsupported status content type %q", contentType)
+	}
+}
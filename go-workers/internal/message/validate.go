@@ -0,0 +1,94 @@
+package message
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mahirjain10/go-workers/internal/transformation"
+	"github.com/mahirjain10/go-workers/internal/types"
+	"github.com/mahirjain10/go-workers/internal/utils"
+	"github.com/mahirjain10/go-workers/internal/validation"
+)
+
+// Validate checks the fields every job needs and the parameters of its type. Size limits are left to the
+// worker, which knows its own.
+func Validate(job types.ImageProcessing) error {
+	required := []struct {
+		name  string
+		value string
+	}{
+		{"id", job.Id},
+		{"userId", job.UserId},
+		{"s3RawKey", job.S3RawKey},
+		{"transformationType", job.TransformationType},
+	}
+	for _, field := range required {
+		if field.value == "" {
+			return fmt.Errorf("%w: %s is required", ErrInvalidMessage, field.name)
+		}
+	}
+	if !strings.Contains(job.S3RawKey, "/") {
+		return fmt.Errorf("%w: s3RawKey %q has no prefix", ErrInvalidMessage, job.S3RawKey)
+	}
+	if job.Priority < 0 {
+		return fmt.Errorf("%w: priority must not be negative, got %d", ErrInvalidMessage, job.Priority)
+	}
+	return ValidateParameters(job.TransformationType, job.TransformationParameters)
+}
+
+// ValidateParameters checks the parameters of a job type, parameter errors wrap
+// transformation.ErrInvalidParameters
+func ValidateParameters(transformationType string, parameters string) error {
+	if transformer, ok := transformation.Lookup(transformationType); ok {
+		operation, err := transformer.Parse(parameters)
+		if err != nil {
+			return err
+		}
+		return operation.Validate(validation.Limits{})
+	}
+	switch transformationType {
+	case "INSPECT":
+		_, err := ParseInspect(parameters)
+		return err
+	case "HISTOGRAM":
+		_, err := ParseHistogram(parameters)
+		return err
+	default:
+		return fmt.Errorf("%w: unsupported transformation type: %s", ErrInvalidMessage, transformationType)
+	}
+}
+
+// ParseInspect decodes the optional INSPECT thresholds, omitted ones fall back to the worker defaults
+func ParseInspect(parameters string) (types.InspectParameters, error) {
+	var inspect types.InspectParameters
+	if parameters == "" {
+		return inspect, nil
+	}
+	if err := utils.ParseStrictJSON([]byte(parameters), &inspect); err != nil {
+		return inspect, fmt.Errorf("%w: INSPECT parameters: %v", transformation.ErrInvalidParameters, err)
+	}
+	if negative(inspect.MinSharpness) || negative(inspect.MinWidth) || negative(inspect.MinHeight) {
+		return inspect, fmt.Errorf("%w: INSPECT thresholds must not be negative", transformation.ErrInvalidParameters)
+	}
+	if clip := inspect.MaxClipping; clip != nil && (*clip < 0 || *clip > 1) {
+		return inspect, fmt.Errorf("%w: INSPECT maxClipping is a fraction between 0 and 1, got %v", transformation.ErrInvalidParameters, *clip)
+	}
+	return inspect, nil
+}
+
+// negative reports whether an optional threshold is set below zero
+func negative[T int | float64](value *T) bool {
+	return value != nil && *value < 0
+}
+
+// ParseHistogram decodes the optional HISTOGRAM parameters
+func ParseHistogram(parameters string) (types.Histogram, error) {
+	var histogram types.Histogram
+	if parameters == "" {
+		return histogram, nil
+	}
+	if err := utils.ParseStrictJSON([]byte(parameters), &histogram); err != nil {
+		return histogram, fmt.Errorf("%w: HISTOGRAM parameters: %v", transformation.ErrInvalidParameters, err)
+	}
+	return histogram, nil
+}
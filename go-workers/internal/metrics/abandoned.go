@@ -0,0 +1,18 @@
+package metrics
+
+import "expvar"
+
+// abandonedTransformations counts transformations still running after their job gave up on them
+var abandonedTransformations = expvar.NewInt("abandoned_transformations")
+
+// TransformationAbandoned records a transformation left running in the background, done must be called
+// once it returns
+func TransformationAbandoned() (done func()) {
+	abandonedTransformations.Add(1)
+	return func() { abandonedTransformations.Add(-1) }
+}
+
+// AbandonedTransformations returns how many abandoned transformations are still running
+func AbandonedTransformations() int64 {
+	return abandonedTransformations.Value()
+}
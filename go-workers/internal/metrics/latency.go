@@ -0,0 +1,69 @@
+package metrics
+
+import (
+	"encoding/json"
+	"expvar"
+	"sync"
+	"time"
+)
+
+// Exported on /debug/vars of the admin endpoint
+var (
+	queueLatency = expvar.NewMap("queue_latency_seconds")
+	expiredJobs  = expvar.NewMap("expired_jobs")
+)
+
+// latencyStats summarises the latencies observed on one queue
+type latencyStats struct {
+	mu    sync.Mutex
+	count int64
+	sum   float64
+	max   float64
+	last  float64
+}
+
+func (s *latencyStats) observe(seconds float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.count++
+	s.sum += seconds
+	s.max = max(s.max, seconds)
+	s.last = seconds
+}
+
+// String renders the stats as the JSON expvar expects
+func (s *latencyStats) String() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	mean := 0.0
+	if s.count > 0 {
+		mean = s.sum / float64(s.count)
+	}
+	out, _ := json.Marshal(map[string]any{
+		"count": s.count,
+		"sum":   s.sum,
+		"mean":  mean,
+		"max":   s.max,
+		"last":  s.last,
+	})
+	return string(out)
+}
+
+var latencyMu sync.Mutex
+
+// ObserveQueueLatency records how long a job waited between its creation and the start of its processing
+func ObserveQueueLatency(queueName string, latency time.Duration) {
+	latencyMu.Lock()
+	stats, ok := queueLatency.Get(queueName).(*latencyStats)
+	if !ok {
+		stats = &latencyStats{}
+		queueLatency.Set(queueName, stats)
+	}
+	latencyMu.Unlock()
+	stats.observe(latency.Seconds())
+}
+
+// IncExpired counts a job rejected because it was older than the maximum job age
+func IncExpired(queueName string) {
+	expiredJobs.Add(queueName, 1)
+}
@@ -0,0 +1,227 @@
+package outbox
+
+import (
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	"github.com/mahirjain10/go-workers/internal/utils"
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	pendingBucket = []byte("pending_messages")
+	// latestBucket maps a job id to the id and time of its newest message
+	latestBucket = []byte("latest_by_job")
+)
+
+// Entry is a message waiting for the broker to confirm it
+type Entry struct {
+	ID uint64 `json:"id"`
+	// JobID orders the messages of one job, a newer message supersedes the pending older ones
+	JobID       string    `json:"jobId,omitempty"`
+	Exchange    string    `json:"exchange"`
+	RoutingKey  string    `json:"routingKey"`
+	ContentType string    `json:"contentType"`
+	Body        []byte    `json:"body"`
+	CreatedAt   time.Time `json:"createdAt"`
+	// Returns counts how often the broker returned the message as unroutable, it is not sent again
+	// before NotBefore
+	Returns   int       `json:"returns,omitempty"`
+	NotBefore time.Time `json:"notBefore,omitempty"`
+}
+
+// Outbox is a durable local queue of messages to publish, backed by a bbolt file. A message is added
+// before it is published and removed only once the broker confirmed it, so nothing is lost when the
+// broker is unreachable or the worker restarts in between. Only the newest message of a job is kept,
+// so a retried older status can never overwrite a newer one.
+type Outbox struct {
+	db *bolt.DB
+}
+
+func Open(path string) (*Outbox, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open outbox %s: %w", path, err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		if _, err := tx.CreateBucketIfNotExists(pendingBucket); err != nil {
+			return err
+		}
+		_, err := tx.CreateBucketIfNotExists(latestBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create outbox bucket: %w", err)
+	}
+	return &Outbox{db: db}, nil
+}
+
+// Add stores a new pending message and returns it with its id. A pending older message of the same
+// job is dropped, it is superseded by this one.
+func (o *Outbox) Add(jobID string, exchange string, routingKey string, contentType string, body []byte) (Entry, error) {
+	entry := Entry{
+		JobID:       jobID,
+		Exchange:    exchange,
+		RoutingKey:  routingKey,
+		ContentType: contentType,
+		Body:        body,
+		CreatedAt:   time.Now().UTC(),
+	}
+	err := o.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(pendingBucket)
+		id, err := bucket.NextSequence()
+		if err != nil {
+			return err
+		}
+		entry.ID = id
+		if jobID != "" {
+			latest := tx.Bucket(latestBucket)
+			if previous, _, ok := decodeLatest(latest.Get([]byte(jobID))); ok {
+				if err := bucket.Delete(key(previous)); err != nil {
+					return err
+				}
+			}
+			if err := latest.Put([]byte(jobID), encodeLatest(id, entry.CreatedAt)); err != nil {
+				return err
+			}
+		}
+		return put(bucket, entry)
+	})
+	if err != nil {
+		return Entry{}, fmt.Errorf("failed to add message to outbox: %w", err)
+	}
+	return entry, nil
+}
+
+// Restore puts a message the broker returned back in the outbox under its original id. It reports
+// false when a newer message of the same job was added since, the returned one is stale then.
+func (o *Outbox) Restore(entry Entry) (bool, error) {
+	restored := false
+	err := o.db.Update(func(tx *bolt.Tx) error {
+		if entry.JobID != "" {
+			latest, _, ok := decodeLatest(tx.Bucket(latestBucket).Get([]byte(entry.JobID)))
+			if ok && latest != entry.ID {
+				return nil
+			}
+		}
+		restored = true
+		return put(tx.Bucket(pendingBucket), entry)
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to restore message %d to outbox: %w", entry.ID, err)
+	}
+	return restored, nil
+}
+
+// Prune forgets the newest message of jobs that have nothing pending and were last written before
+// now minus retention. A message returned after that could overwrite a newer status, keep retention
+// well above the confirm timeout.
+func (o *Outbox) Prune(retention time.Duration) error {
+	cutoff := time.Now().Add(-retention)
+	err := o.db.Update(func(tx *bolt.Tx) error {
+		pending := tx.Bucket(pendingBucket)
+		latest := tx.Bucket(latestBucket)
+		var stale [][]byte
+		err := latest.ForEach(func(jobID, value []byte) error {
+			id, written, ok := decodeLatest(value)
+			if !ok || (written.Before(cutoff) && pending.Get(key(id)) == nil) {
+				stale = append(stale, append([]byte(nil), jobID...))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, jobID := range stale {
+			if err := latest.Delete(jobID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to prune outbox: %w", err)
+	}
+	return nil
+}
+
+// Remove drops a confirmed message
+func (o *Outbox) Remove(id uint64) error {
+	err := o.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(pendingBucket).Delete(key(id))
+	})
+	if err != nil {
+		return fmt.Errorf("failed to remove message %d from outbox: %w", id, err)
+	}
+	return nil
+}
+
+// Contains reports whether the message is still pending
+func (o *Outbox) Contains(id uint64) (bool, error) {
+	found := false
+	err := o.db.View(func(tx *bolt.Tx) error {
+		found = tx.Bucket(pendingBucket).Get(key(id)) != nil
+		return nil
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to read outbox: %w", err)
+	}
+	return found, nil
+}
+
+// Pending returns every unconfirmed message, oldest first
+func (o *Outbox) Pending() ([]Entry, error) {
+	var entries []Entry
+	err := o.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(pendingBucket).ForEach(func(_, value []byte) error {
+			var entry Entry
+			if err := utils.ParseJSON(value, &entry); err != nil {
+				return err
+			}
+			entries = append(entries, entry)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read outbox: %w", err)
+	}
+	return entries, nil
+}
+
+func (o *Outbox) Close() error {
+	return o.db.Close()
+}
+
+func put(bucket *bolt.Bucket, entry Entry) error {
+	value, err := utils.SerializeJSON(entry)
+	if err != nil {
+		return err
+	}
+	return bucket.Put(key(entry.ID), value)
+}
+
+// encodeLatest packs the id and write time of a job's newest message
+func encodeLatest(id uint64, written time.Time) []byte {
+	b := make([]byte, 16)
+	binary.BigEndian.PutUint64(b, id)
+	binary.BigEndian.PutUint64(b[8:], uint64(written.UnixNano()))
+	return b
+}
+
+func decodeLatest(value []byte) (uint64, time.Time, bool) {
+	if len(value) != 16 {
+		return 0, time.Time{}, false
+	}
+	id := binary.BigEndian.Uint64(value)
+	written := time.Unix(0, int64(binary.BigEndian.Uint64(value[8:])))
+	return id, written, true
+}
+
+// key encodes ids big endian so the bucket iterates in insertion order
+func key(id uint64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, id)
+	return b
+}
@@ -0,0 +1,155 @@
+package outbox
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func openTest(t *testing.T) *Outbox {
+	t.Helper()
+	o, err := Open(filepath.Join(t.TempDir(), "outbox.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { o.Close() })
+	return o
+}
+
+func pendingBodies(t *testing.T, o *Outbox) []string {
+	t.Helper()
+	entries, err := o.Pending()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var bodies []string
+	for _, entry := range entries {
+		bodies = append(bodies, string(entry.Body))
+	}
+	return bodies
+}
+
+func add(t *testing.T, o *Outbox, jobID string, body string) Entry {
+	t.Helper()
+	entry, err := o.Add(jobID, "status", "status", "application/json", []byte(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return entry
+}
+
+func TestAddSupersedesOlderMessagesOfTheJob(t *testing.T) {
+	o := openTest(t)
+	add(t, o, "job-1", "job-1 PROCESSING")
+	add(t, o, "job-2", "job-2 PROCESSING")
+	add(t, o, "job-1", "job-1 PROCESSED")
+	add(t, o, "", "no job")
+	add(t, o, "", "no job either")
+
+	got := pendingBodies(t, o)
+	want := []string{"job-2 PROCESSING", "job-1 PROCESSED", "no job", "no job either"}
+	if len(got) != len(want) {
+		t.Fatalf("Pending() = %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("Pending() = %q, want %q", got, want)
+		}
+	}
+}
+
+func TestRestore(t *testing.T) {
+	tests := []struct {
+		name         string
+		newerMessage bool
+		wantRestored bool
+	}{
+		{name: "newest message of the job is restored", wantRestored: true},
+		{name: "superseded message is dropped", newerMessage: true, wantRestored: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := openTest(t)
+			entry := add(t, o, "job-1", "PROCESSING")
+			// The broker acked the message after returning it
+			if err := o.Remove(entry.ID); err != nil {
+				t.Fatal(err)
+			}
+			if tt.newerMessage {
+				newer := add(t, o, "job-1", "PROCESSED")
+				if err := o.Remove(newer.ID); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			entry.Returns = 1
+			restored, err := o.Restore(entry)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if restored != tt.wantRestored {
+				t.Fatalf("Restore() = %t, want %t", restored, tt.wantRestored)
+			}
+			pending, err := o.Contains(entry.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if pending != tt.wantRestored {
+				t.Fatalf("Contains() = %t, want %t", pending, tt.wantRestored)
+			}
+		})
+	}
+}
+
+func TestPruneKeepsJobsWithPendingMessages(t *testing.T) {
+	o := openTest(t)
+	confirmed := add(t, o, "job-1", "PROCESSED")
+	if err := o.Remove(confirmed.ID); err != nil {
+		t.Fatal(err)
+	}
+	pending := add(t, o, "job-2", "PROCESSING")
+
+	if err := o.Prune(-time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	// job-1 is forgotten, so its old message is accepted again; job-2 still supersedes older ones
+	if restored, err := o.Restore(confirmed); err != nil || !restored {
+		t.Fatalf("Restore() of a pruned job = %t, %v, want true", restored, err)
+	}
+	stale := pending
+	stale.ID = 0
+	if restored, err := o.Restore(stale); err != nil || restored {
+		t.Fatalf("Restore() of a superseded message = %t, %v, want false", restored, err)
+	}
+}
+
+func TestPendingKeepsInsertionOrderAcrossReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "outbox.db")
+	o, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Past 255 ids a little endian key would sort id 256 before id 1
+	for i := 0; i < 300; i++ {
+		add(t, o, "", "message")
+	}
+	o.Close()
+
+	o, err = Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer o.Close()
+	entries, err := o.Pending()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 300 {
+		t.Fatalf("Pending() returned %d messages, want 300", len(entries))
+	}
+	for i, entry := range entries {
+		if entry.ID != uint64(i+1) {
+			t.Fatalf("message %d has id %d, want %d", i, entry.ID, i+1)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package placeholder
+
+import (
+	"image"
+	"image/color"
+	"strings"
+	"testing"
+)
+
+// The expected hashes are worked out by hand from the reference algorithm at https://blurha.sh
+func TestEncodeBlurHash(t *testing.T) {
+	white := color.NRGBA{R: 255, G: 255, B: 255, A: 255}
+	black := color.NRGBA{A: 255}
+	tests := []struct {
+		name        string
+		img         image.Image
+		xComponents int
+		yComponents int
+		want        string
+	}{
+		{
+			// size flag 3+2*9 = 21 "L", DC 0xFFFFFF "TSUA". The basis is not centred on the pixels, so on a
+			// 4x4 image the odd cosines sum to 1: those AC terms are 0.5 "~q" (the maximum, quantised "~"),
+			// their products 0.125 "%M" and the even ones 0 "fQ"
+			name:        "solid white 4x3",
+			img:         filled(4, 4, func(x, y int) color.Color { return white }),
+			xComponents: 4,
+			yComponents: 3,
+			want:        "L~TSUA" + "~qfQ~q" + "~q%MfQ%M" + strings.Repeat("fQ", 4),
+		},
+		{
+			// left half white, right half black: DC is linear 0.5 = sRGB 188 "Lqe9", the one AC term
+			// saturates the quantisation so both the maximum "~" and the term "~q" are at their limit
+			name: "white then black 2x1",
+			img: filled(4, 1, func(x, y int) color.Color {
+				if x < 2 {
+					return white
+				}
+				return black
+			}),
+			xComponents: 2,
+			yComponents: 1,
+			want:        "1~Lqe9~q",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := EncodeBlurHash(tt.img, tt.xComponents, tt.yComponents)
+			if err != nil {
+				t.Fatalf("EncodeBlurHash() = %v", err)
+			}
+			if got != tt.want {
+				t.Fatalf("EncodeBlurHash() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEncodeBlurHashRejectsBadInput(t *testing.T) {
+	img := filled(2, 2, func(x, y int) color.Color { return color.Black })
+	for _, components := range [][2]int{{0, 3}, {4, 10}} {
+		if _, err := EncodeBlurHash(img, components[0], components[1]); err == nil {
+			t.Errorf("EncodeBlurHash(%dx%d components) did not fail", components[0], components[1])
+		}
+	}
+	if _, err := EncodeBlurHash(image.NewNRGBA(image.Rect(0, 0, 0, 0)), 4, 3); err == nil {
+		t.Error("EncodeBlurHash(empty image) did not fail")
+	}
+}
+
+func filled(width int, height int, at func(x, y int) color.Color) image.Image {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, at(x, y))
+		}
+	}
+	return img
+}
@@ -0,0 +1,55 @@
+package placeholder
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+
+	"github.com/disintegration/imaging"
+)
+
+const (
+	// BlurHash is computed on a small thumbnail, the hash only keeps a handful of components anyway
+	blurHashSampleSize = 32
+	blurHashXComponent = 4
+	blurHashYComponent = 3
+
+	lqipWidth   = 16
+	lqipQuality = 40
+)
+
+// Generate decodes the image buffer and returns its BlurHash and a base64 encoded
+// JPEG data URI small enough to be inlined as a low quality image placeholder (LQIP).
+func Generate(buffer []byte) (string, string, error) {
+	img, _, err := image.Decode(bytes.NewReader(buffer))
+	if err != nil {
+		return "", "", fmt.Errorf("failed to decode image: %v", err)
+	}
+
+	sample := imaging.Fit(img, blurHashSampleSize, blurHashSampleSize, imaging.Box)
+	blurHash, err := EncodeBlurHash(sample, blurHashXComponent, blurHashYComponent)
+	if err != nil {
+		return "", "", err
+	}
+
+	lqip, err := encodeLQIP(img)
+	if err != nil {
+		return "", "", err
+	}
+	return blurHash, lqip, nil
+}
+
+func encodeLQIP(img image.Image) (string, error) {
+	thumbnail := imaging.Resize(img, lqipWidth, 0, imaging.Lanczos)
+
+	buf := new(bytes.Buffer)
+	if err := imaging.Encode(buf, thumbnail, imaging.JPEG, imaging.JPEGQuality(lqipQuality)); err != nil {
+		return "", fmt.Errorf("error while encoding placeholder: %v", err)
+	}
+	return "data:image/jpeg;base64," + base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
@@ -0,0 +1,34 @@
+package queue
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/mahirjain10/go-workers/internal/metrics"
+)
+
+// abandonedPollInterval is how often a paused consumer checks whether abandoned transformations finished
+const abandonedPollInterval = time.Second
+
+// waitForAbandoned holds the next job while MaxAbandoned or more abandoned transformations still run, each
+// of them keeps a CPU and its share of the memory budget. It returns false when stop is done first.
+func (rabbitMqService *RabbitMqService) waitForAbandoned(stop context.Context, queueName string) bool {
+	limit := int64(rabbitMqService.config.MaxAbandoned)
+	if limit <= 0 || metrics.AbandonedTransformations() < limit {
+		return true
+	}
+	log.Printf("[%s] %d abandoned transformations still running, pausing until they finish", queueName, metrics.AbandonedTransformations())
+
+	ticker := time.NewTicker(abandonedPollInterval)
+	defer ticker.Stop()
+	for metrics.AbandonedTransformations() >= limit {
+		select {
+		case <-stop.Done():
+			return false
+		case <-ticker.C:
+		}
+	}
+	log.Printf("[%s] abandoned transformations finished, resuming", queueName)
+	return true
+}
@@ -0,0 +1,87 @@
+package queue
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/mahirjain10/go-workers/internal/utils"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// autoscale resizes every worker pool from its queue depth and in-flight jobs each interval until ctx is
+// done, see autoscaleStep.
+func (rabbitMqService *RabbitMqService) autoscale(ctx context.Context) {
+	autoscaleConfig := rabbitMqService.config.Autoscale
+	ticker := time.NewTicker(autoscaleConfig.Interval)
+	defer ticker.Stop()
+
+	var ch *amqp.Channel
+	defer func() {
+		if ch != nil {
+			ch.Close()
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if ch == nil || ch.IsClosed() {
+			newCh, err := rabbitMqService.connections.channel(ctx)
+			if err != nil {
+				return
+			}
+			ch = newCh
+		}
+
+		rabbitMqService.poolsMu.RLock()
+		pools := make(map[string]*workerPool, len(rabbitMqService.pools))
+		for queueName, pool := range rabbitMqService.pools {
+			pools[queueName] = pool
+		}
+		rabbitMqService.poolsMu.RUnlock()
+
+		for queueName, pool := range pools {
+			depth, err := utils.QueueDepth(ch, queueName)
+			if err != nil {
+				log.Printf("[autoscaler] %v", err)
+				// A failed passive declare closes the channel, open a new one on the next tick
+				break
+			}
+
+			current, busy := pool.size(), pool.busy()
+			wanted := autoscaleStep(current, busy, rabbitMqService.autoscaleTarget(queueName, depth, busy))
+			if wanted != current {
+				log.Printf("[autoscaler] [%s] %d ready and %d in-flight messages, scaling from %d to %d workers", queueName, depth, busy, current, wanted)
+				pool.scale(wanted)
+			}
+		}
+	}
+}
+
+// autoscaleTarget is one worker per MessagesPerWorker messages, ready or in flight, within the queue's
+// bounds. The passive declare only counts ready messages, the jobs the consumers hold are added so a
+// queue drained into long running jobs does not look idle.
+func (rabbitMqService *RabbitMqService) autoscaleTarget(queueName string, depth int, inFlight int) int {
+	autoscaleConfig := rabbitMqService.config.Autoscale
+	bounds := autoscaleConfig.Bounds[queueName]
+	wanted := (depth + inFlight + autoscaleConfig.MessagesPerWorker - 1) / autoscaleConfig.MessagesPerWorker
+	return min(max(wanted, bounds.Min), bounds.Max)
+}
+
+// autoscaleStep returns the worker count for the next tick. Scaling up jumps straight to wanted, scaling
+// down stops one worker per tick and only while one of them is idle, so a short lull between bursts
+// does not tear down the whole pool.
+func autoscaleStep(current int, busy int, wanted int) int {
+	if wanted >= current {
+		return wanted
+	}
+	if busy >= current {
+		return current
+	}
+	return max(wanted, current-1)
+}
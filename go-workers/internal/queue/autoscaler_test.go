@@ -0,0 +1,55 @@
+package queue
+
+import (
+	"testing"
+
+	"github.com/mahirjain10/go-workers/config"
+)
+
+func TestAutoscaleTarget(t *testing.T) {
+	rabbitMqService := &RabbitMqService{config: &config.Config{Autoscale: config.AutoscaleConfig{
+		MessagesPerWorker: 10,
+		Bounds:            map[string]config.WorkerBounds{"resize_queue": {Min: 1, Max: 5}},
+	}}}
+	tests := []struct {
+		name     string
+		depth    int
+		inFlight int
+		want     int
+	}{
+		{name: "empty queue keeps the minimum", want: 1},
+		{name: "one worker per ten messages", depth: 25, want: 3},
+		{name: "in-flight jobs count", depth: 5, inFlight: 10, want: 2},
+		{name: "drained into in-flight jobs", inFlight: 21, want: 3},
+		{name: "capped at the maximum", depth: 200, inFlight: 5, want: 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := rabbitMqService.autoscaleTarget("resize_queue", tt.depth, tt.inFlight); got != tt.want {
+				t.Fatalf("autoscaleTarget() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAutoscaleStep(t *testing.T) {
+	tests := []struct {
+		name                  string
+		current, busy, wanted int
+		want                  int
+	}{
+		{name: "scale up jumps to wanted", current: 1, busy: 1, wanted: 4, want: 4},
+		{name: "steady", current: 3, busy: 1, wanted: 3, want: 3},
+		{name: "scale down by one per tick", current: 4, busy: 0, wanted: 1, want: 3},
+		{name: "scale down stops at wanted", current: 2, busy: 0, wanted: 1, want: 1},
+		{name: "every worker busy", current: 4, busy: 4, wanted: 1, want: 4},
+		{name: "one idle worker", current: 4, busy: 3, wanted: 1, want: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := autoscaleStep(tt.current, tt.busy, tt.wanted); got != tt.want {
+				t.Fatalf("autoscaleStep(%d, %d, %d) = %d, want %d", tt.current, tt.busy, tt.wanted, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,221 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	queueErrors "github.com/mahirjain10/go-workers/internal/queue/errors"
+	"github.com/mahirjain10/go-workers/internal/types"
+	"github.com/mahirjain10/go-workers/internal/utils"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// cancellations remembers cancelled job ids for ttl, so a job cancelled while queued is dropped when it is
+// consumed, and aborts the context of a cancelled job that is already running
+type cancellations struct {
+	ttl time.Duration
+
+	mu        sync.Mutex
+	cancelled map[string]time.Time
+	inFlight  map[string]*inFlightJob
+}
+
+type inFlightJob struct {
+	cancel context.CancelCauseFunc
+}
+
+// cancelPruneInterval is how often expired cancels are forgotten
+const cancelPruneInterval = time.Minute
+
+func newCancellations(ttl time.Duration) *cancellations {
+	return &cancellations{
+		ttl:       ttl,
+		cancelled: make(map[string]time.Time),
+		inFlight:  make(map[string]*inFlightJob),
+	}
+}
+
+// cancel records the cancel of id and aborts it when it is running
+func (c *cancellations) cancel(id string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.cancelled[id] = time.Now().Add(c.ttl)
+	if job, ok := c.inFlight[id]; ok {
+		job.cancel(queueErrors.ErrCancelled)
+	}
+}
+
+// prune forgets the cancels that expired before now
+func (c *cancellations) prune(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for id, expiresAt := range c.cancelled {
+		if now.After(expiresAt) {
+			delete(c.cancelled, id)
+		}
+	}
+}
+
+// pruneEvery prunes expired cancels every interval until ctx is done
+func (c *cancellations) pruneEvery(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			c.prune(now)
+		}
+	}
+}
+
+func (c *cancellations) isCancelled(id string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	expiresAt, ok := c.cancelled[id]
+	return ok && time.Now().Before(expiresAt)
+}
+
+// track returns the context job id runs with, a cancel aborts it with queueErrors.ErrCancelled as cause.
+// done must be called once the job is over.
+func (c *cancellations) track(ctx context.Context, id string) (context.Context, func()) {
+	jobCtx, cancel := context.WithCancelCause(ctx)
+	job := &inFlightJob{cancel: cancel}
+
+	c.mu.Lock()
+	c.inFlight[id] = job
+	c.mu.Unlock()
+
+	return jobCtx, func() {
+		c.mu.Lock()
+		if c.inFlight[id] == job {
+			delete(c.inFlight, id)
+		}
+		c.mu.Unlock()
+		cancel(nil)
+	}
+}
+
+// cancelMessage is a cancel published to the control exchange, either {"id": ...} or the
+// {"pattern": "cancel", "data": {"id": ...}} envelope NestJS clients emit
+type cancelMessage struct {
+	ID   string `json:"id"`
+	Data struct {
+		ID string `json:"id"`
+	} `json:"data"`
+}
+
+func (m cancelMessage) jobID() string {
+	if m.ID != "" {
+		return m.ID
+	}
+	return m.Data.ID
+}
+
+// parseCancel returns the job id a cancel message names, ok is false when it is malformed
+func parseCancel(body []byte) (string, bool) {
+	var message cancelMessage
+	if err := json.Unmarshal(body, &message); err != nil || message.jobID() == "" {
+		return "", false
+	}
+	return message.jobID(), true
+}
+
+// consumeCancellations listens to the control exchange on a queue of this worker's own, so every replica
+// aborts the cancelled job it is running
+func (rabbitMqService *RabbitMqService) consumeCancellations(ctx context.Context) {
+	rabbitMqService.consumeControl(ctx, "control", utils.NewControlConsumer, func(d amqp.Delivery) {
+		id, ok := parseCancel(d.Body)
+		if !ok {
+			log.Printf("[control] ignoring malformed cancel %s", d.Body)
+			return
+		}
+		log.Printf("[control] cancel received for job %s", id)
+		rabbitMqService.cancellations.cancel(id)
+	})
+}
+
+// consumeStoredCancellations records the cancels kept in the durable cancel queue, including those published
+// while no worker was connected, in the idempotency store. A job recorded there as CANCELLED is dropped
+// whichever replica consumes it, see replayCompleted, so the store decides how far a cancel reaches: the
+// redis store shares it between replicas, memory and bolt only with this one.
+func (rabbitMqService *RabbitMqService) consumeStoredCancellations(ctx context.Context) {
+	rabbitMqService.consumeControl(ctx, "cancels", utils.NewCancelQueueConsumer, func(d amqp.Delivery) {
+		id, ok := parseCancel(d.Body)
+		if !ok {
+			log.Printf("[cancels] dropping malformed cancel %s", d.Body)
+			d.Nack(false, false)
+			return
+		}
+		rabbitMqService.cancellations.cancel(id)
+		if err := rabbitMqService.storeCancel(ctx, id); err != nil {
+			log.Printf("[cancels] %v, requeueing it", err)
+			d.Nack(false, true)
+			return
+		}
+		d.Ack(false)
+	})
+}
+
+// storeCancel records job id as CANCELLED unless it already completed
+func (rabbitMqService *RabbitMqService) storeCancel(ctx context.Context, id string) error {
+	if _, completed, err := rabbitMqService.idempotencyStore.Get(ctx, id); err != nil || completed {
+		return err
+	}
+	statusData := utils.InitStatusData(id, "", types.CANCELLED, "", queueErrors.ErrCancelled.Msg)
+	if err := rabbitMqService.idempotencyStore.Put(ctx, id, statusData); err != nil {
+		return fmt.Errorf("failed to record cancel of job %s: %w", id, err)
+	}
+	return nil
+}
+
+// consumeControl hands every delivery of the queue consume opens to handle until ctx is done, reopening its
+// channel whenever the connection manager reconnects
+func (rabbitMqService *RabbitMqService) consumeControl(ctx context.Context, name string, consume func(*amqp.Channel) (<-chan amqp.Delivery, error), handle func(amqp.Delivery)) {
+	for {
+		ch, err := rabbitMqService.connections.channel(ctx)
+		if err != nil {
+			return
+		}
+		msgs, err := consume(ch)
+		if err != nil {
+			log.Printf("[%s] %v", name, err)
+			ch.Close()
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(5 * time.Second):
+			}
+			continue
+		}
+		log.Printf("[%s] listening for job cancels", name)
+
+		for open := true; open; {
+			select {
+			case <-ctx.Done():
+				ch.Close()
+				return
+			case d, ok := <-msgs:
+				if !ok {
+					log.Printf("[%s] channel closed, will recreate", name)
+					open = false
+					break
+				}
+				handle(d)
+			}
+		}
+	}
+}
+
+// publishCancelled reports a dropped or aborted job
+func (rabbitMqService *RabbitMqService) publishCancelled(ctx context.Context, data types.ImageProcessing) error {
+	statusData := utils.InitStatusData(data.Id, data.UserId, types.CANCELLED, "", queueErrors.ErrCancelled.Msg)
+	statusData.ErrorCode = string(queueErrors.CodeCancelled)
+	return rabbitMqService.PublishStatusData(ctx, statusData)
+}
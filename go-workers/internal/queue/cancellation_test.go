@@ -0,0 +1,87 @@
+package queue
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mahirjain10/go-workers/internal/idempotency"
+	"github.com/mahirjain10/go-workers/internal/types"
+	"github.com/mahirjain10/go-workers/internal/utils"
+)
+
+func TestParseCancel(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		id   string
+		ok   bool
+	}{
+		{name: "plain", body: `{"id": "job"}`, id: "job", ok: true},
+		{name: "nest envelope", body: `{"pattern": "cancel", "data": {"id": "job"}}`, id: "job", ok: true},
+		{name: "no id", body: `{"data": {}}`},
+		{name: "not json", body: `cancel job`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id, ok := parseCancel([]byte(tt.body))
+			if id != tt.id || ok != tt.ok {
+				t.Fatalf("parseCancel() = %q, %t, want %q, %t", id, ok, tt.id, tt.ok)
+			}
+		})
+	}
+}
+
+func TestCancellationsPrune(t *testing.T) {
+	c := newCancellations(time.Minute)
+	c.cancel("job")
+	c.prune(time.Now())
+	if !c.isCancelled("job") {
+		t.Fatal("prune forgot a cancel that had not expired")
+	}
+	c.prune(time.Now().Add(2 * time.Minute))
+	if len(c.cancelled) != 0 {
+		t.Fatalf("prune kept %d expired cancels", len(c.cancelled))
+	}
+}
+
+func TestCancellationsAbortRunningJob(t *testing.T) {
+	c := newCancellations(time.Minute)
+	jobCtx, done := c.track(context.Background(), "job")
+	defer done()
+	c.cancel("job")
+	if jobCtx.Err() == nil {
+		t.Fatal("cancel did not abort the running job")
+	}
+}
+
+func TestStoreCancel(t *testing.T) {
+	store := idempotency.NewMemoryStore(0)
+	rabbitMqService := &RabbitMqService{idempotencyStore: store}
+	ctx := context.Background()
+
+	completed := utils.InitStatusData("done", "user", types.PROCCESSED, "https://example.com/done.png", "")
+	if err := store.Put(ctx, "done", completed); err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"queued", "done"} {
+		if err := rabbitMqService.storeCancel(ctx, id); err != nil {
+			t.Fatalf("storeCancel(%s) = %v", id, err)
+		}
+	}
+
+	tests := []struct {
+		id     string
+		status string
+	}{
+		{id: "queued", status: types.CANCELLED},
+		// A cancel that lands after the job completed must not overwrite its status
+		{id: "done", status: types.PROCCESSED},
+	}
+	for _, tt := range tests {
+		statusData, ok, err := store.Get(ctx, tt.id)
+		if err != nil || !ok || statusData.Status != tt.status {
+			t.Fatalf("job %s recorded as %+v, %t, %v, want %s", tt.id, statusData, ok, err, tt.status)
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/mahirjain10/go-workers/internal/retry"
+	"github.com/mahirjain10/go-workers/internal/utils"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// reconnectBackoff spaces out dial attempts while the broker is unreachable, it is retried forever
+var reconnectBackoff = retry.Policy{
+	BaseDelay:  time.Second,
+	MaxDelay:   30 * time.Second,
+	Multiplier: 2,
+	Jitter:     0.2,
+}
+
+var errConnectionClosed = errors.New("rabbitmq connection manager is closed")
+
+// connectionManager owns the one RabbitMQ connection shared by every worker. It watches the connection
+// with NotifyClose, dials again with backoff when it is lost, runs setup on the new connection
+// (topology and status channel) and only then hands it out again to the consumers waiting in channel.
+type connectionManager struct {
+	url   string
+	setup func(conn *amqp.Connection) error
+
+	mu   sync.Mutex
+	conn *amqp.Connection
+	// ready is closed once conn is connected and set up, it is replaced while reconnecting
+	ready chan struct{}
+	// done is closed by close, it stops the reconnect loop
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func newConnectionManager(url string, conn *amqp.Connection, setup func(conn *amqp.Connection) error) *connectionManager {
+	return &connectionManager{
+		url:   url,
+		setup: setup,
+		conn:  conn,
+		ready: make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+}
+
+// start sets up the initial connection and starts watching it
+func (m *connectionManager) start() error {
+	m.mu.Lock()
+	conn := m.conn
+	m.mu.Unlock()
+
+	if err := m.setup(conn); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	close(m.ready)
+	m.mu.Unlock()
+
+	go m.watch(conn)
+	return nil
+}
+
+// watch waits for conn to close and replaces it, until close is called
+func (m *connectionManager) watch(conn *amqp.Connection) {
+	for {
+		closed := conn.NotifyClose(make(chan *amqp.Error, 1))
+		select {
+		case <-m.done:
+			return
+		case amqpErr := <-closed:
+			select {
+			case <-m.done:
+				return
+			default:
+			}
+			log.Printf("[connection] connection to RabbitMQ lost: %v, reconnecting", amqpErr)
+		}
+
+		m.mu.Lock()
+		m.ready = make(chan struct{})
+		m.mu.Unlock()
+
+		conn = m.reconnect()
+		if conn == nil {
+			return
+		}
+
+		m.mu.Lock()
+		select {
+		case <-m.done:
+			// close ran while dialing and could not see this connection
+			m.mu.Unlock()
+			conn.Close()
+			return
+		default:
+		}
+		m.conn = conn
+		close(m.ready)
+		m.mu.Unlock()
+		log.Println("[connection] reconnected to RabbitMQ")
+	}
+}
+
+// reconnect dials until a connection is up and set up, it returns nil once the manager is closed
+func (m *connectionManager) reconnect() *amqp.Connection {
+	for attempt := 1; ; attempt++ {
+		conn, err := utils.NewRabbitMQClient(m.url)
+		if err == nil {
+			if err = m.setup(conn); err == nil {
+				return conn
+			}
+			conn.Close()
+		}
+
+		delay := reconnectBackoff.Backoff(attempt)
+		log.Printf("[connection] reconnect attempt %d failed, retrying in %s: %v", attempt, delay, err)
+		timer := time.NewTimer(delay)
+		select {
+		case <-m.done:
+			timer.Stop()
+			return nil
+		case <-timer.C:
+		}
+	}
+}
+
+// channel opens a channel on the current connection, waiting for a reconnect if the connection is down
+func (m *connectionManager) channel(ctx context.Context) (*amqp.Channel, error) {
+	for {
+		m.mu.Lock()
+		conn, ready := m.conn, m.ready
+		m.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-m.done:
+			return nil, errConnectionClosed
+		case <-ready:
+		}
+
+		ch, err := utils.NewChannel(conn)
+		if err == nil {
+			return ch, nil
+		}
+		// The connection died but watch has not noticed yet, give it a moment to swap in a new one
+		log.Printf("[connection] %v, waiting for reconnect", err)
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+// close stops reconnecting and closes the current connection, along with all of its channels
+func (m *connectionManager) close() error {
+	var err error
+	m.closeOnce.Do(func() {
+		close(m.done)
+		m.mu.Lock()
+		conn := m.conn
+		m.mu.Unlock()
+		if conn != nil && !conn.IsClosed() {
+			err = conn.Close()
+		}
+	})
+	return err
+}
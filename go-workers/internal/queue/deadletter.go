@@ -0,0 +1,105 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os"
+	"time"
+
+	"github.com/mahirjain10/go-workers/internal/admission"
+	queueErrors "github.com/mahirjain10/go-workers/internal/queue/errors"
+	"github.com/mahirjain10/go-workers/internal/queue/models"
+	"github.com/mahirjain10/go-workers/internal/utils"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// isRetryable decides whether a failed delivery deserves another attempt
+func isRetryable(err error) bool {
+	var procErr models.ProcessingError
+	if errors.As(err, &procErr) {
+		return procErr.Requeue
+	}
+	return queueErrors.IsTransient(err)
+}
+
+// handleFailure moves a failed delivery to the next retry tier, or to the dead letter queue once it is not
+// retryable or every tier was used, and acks the original once the broker confirmed the copy. The delivery
+// is requeued if that publish fails so the message is never dropped. A retried job only loses its local
+// files, a dead lettered one gets its FAILED status and its cleanup, S3 included.
+func (rabbitMqService *RabbitMqService) handleFailure(ctx context.Context, ch *amqp.Channel, queueName string, d amqp.Delivery, procErr error) {
+	// The budget is acquired after the download, within the job deadline. A job that ran out of time
+	// waiting for it did nothing wrong, so it goes back to the queue without using a retry tier.
+	if errors.Is(procErr, admission.ErrBudgetUnavailable) {
+		rabbitMqService.requeueForBudget(queueName, d, procErr)
+		return
+	}
+
+	attempt := utils.AttemptCount(d)
+	tiers := rabbitMqService.config.RetryTiers
+
+	headers := amqp.Table{}
+	for key, value := range d.Headers {
+		headers[key] = value
+	}
+	headers[utils.HeaderFailureReason] = procErr.Error()
+	var jobErr *queueErrors.Error
+	if errors.As(procErr, &jobErr) {
+		headers[utils.HeaderErrorCode] = string(jobErr.Code)
+	}
+	headers[utils.HeaderFailedQueue] = queueName
+	headers[utils.HeaderFailedAt] = time.Now().UTC().Format(time.RFC3339)
+
+	exchange, routingKey := utils.DeadLetterExchange, queueName
+	retrying := isRetryable(procErr) && attempt < len(tiers)
+	if retrying {
+		headers[utils.HeaderAttempt] = int32(attempt + 1)
+		exchange, routingKey = "", utils.RetryQueueName(queueName, tiers[attempt])
+	}
+
+	err := utils.PublishConfirmed(ctx, ch, exchange, routingKey, amqp.Publishing{
+		ContentType:  d.ContentType,
+		DeliveryMode: amqp.Persistent,
+		Priority:     d.Priority,
+		Headers:      headers,
+		Body:         d.Body,
+	})
+	if err != nil {
+		log.Printf("[%s] failed to route failed message to %s, requeueing: %v", queueName, routingKey, err)
+		d.Nack(false, true)
+		return
+	}
+	log.Printf("[%s] failed message (attempt %d) routed to %s", queueName, attempt+1, routingKey)
+
+	var failure models.ProcessingError
+	if errors.As(procErr, &failure) && failure.Job != nil {
+		job := *failure.Job
+		_, downloadPath, uploadPath := rabbitMqService.s3Service.GetDependencyData()
+		if retrying {
+			// The next attempt downloads the raw object again
+			rabbitMqService.fireBackgroundCleanup(ctx, downloadPath, uploadPath, job.S3RawKey, "remove_local_all")
+		} else {
+			if err := rabbitMqService.publishFailed(ctx, job, failure.Err); err != nil {
+				log.Printf("[%s] failed to publish FAILED status of job %s: %v", queueName, job.Id, err)
+			}
+			if failure.Cleanup != "" {
+				rabbitMqService.fireBackgroundCleanup(ctx, downloadPath, uploadPath, job.S3RawKey, failure.Cleanup)
+			}
+		}
+	}
+	d.Ack(false)
+}
+
+// requeueForBudget returns a delivery that timed out waiting for the memory budget to its queue. Its
+// raw file is removed first, the next attempt downloads it again and may run on this worker.
+func (rabbitMqService *RabbitMqService) requeueForBudget(queueName string, d amqp.Delivery, procErr error) {
+	var failure models.ProcessingError
+	if errors.As(procErr, &failure) && failure.Job != nil {
+		_, downloadPath, _ := rabbitMqService.s3Service.GetDependencyData()
+		if err := utils.RemoveLocalRaw(downloadPath, failure.Job.S3RawKey); err != nil && !errors.Is(err, os.ErrNotExist) {
+			log.Printf("[%s] error while removing local raw file %v", queueName, err)
+		}
+	}
+	log.Printf("[%s] memory budget unavailable, requeueing: %v", queueName, procErr)
+	d.Nack(false, true)
+}
@@ -0,0 +1,30 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/mahirjain10/go-workers/internal/admission"
+	queueErrors "github.com/mahirjain10/go-workers/internal/queue/errors"
+	"github.com/mahirjain10/go-workers/internal/types"
+)
+
+func TestBudgetTimeoutStaysRecognisable(t *testing.T) {
+	ctx, cancel := context.WithCancelCause(context.Background())
+	cancel(queueErrors.ErrTimeout)
+
+	rabbitMqService := &RabbitMqService{}
+	data := types.ImageProcessing{Id: "job", UserId: "user", S3RawKey: "raw/job.png"}
+	cause := fmt.Errorf("transform: %w", fmt.Errorf("%w: %v", admission.ErrBudgetUnavailable, context.DeadlineExceeded))
+	err := rabbitMqService.jobFailed(ctx, data, cause, queueErrors.ErrTransform, "remove_local_and_delete_s3")
+
+	// The deadline makes it a TIMEOUT that is not retried, handleFailure requeues it before looking at that
+	if !errors.Is(err, queueErrors.ErrTimeout) || isRetryable(err) {
+		t.Fatalf("jobFailed() = %v, want a TIMEOUT that is not retried", err)
+	}
+	if !errors.Is(err, admission.ErrBudgetUnavailable) {
+		t.Fatalf("jobFailed() = %v, lost ErrBudgetUnavailable", err)
+	}
+}
@@ -0,0 +1,29 @@
+package queue
+
+import (
+	"context"
+
+	"github.com/mahirjain10/go-workers/internal/transformation"
+	"github.com/mahirjain10/go-workers/internal/types"
+)
+
+// jobHandler finishes a job once its raw image is downloaded, publishing the final status
+type jobHandler func(rabbitMqService *RabbitMqService, ctx context.Context, data types.ImageProcessing, downloadPath string, uploadPath string) error
+
+// jobHandlers holds the job types that are not image transformations
+var jobHandlers = map[string]jobHandler{
+	"INSPECT":   (*RabbitMqService).processInspection,
+	"HISTOGRAM": (*RabbitMqService).processHistogram,
+}
+
+// handlerFor routes a job by its TransformationType, whichever queue it came from. Every registered
+// transformation.Transformer is routable without touching this file.
+func handlerFor(transformationType string) (jobHandler, bool) {
+	if handler, ok := jobHandlers[transformationType]; ok {
+		return handler, true
+	}
+	if _, ok := transformation.Lookup(transformationType); ok {
+		return (*RabbitMqService).processTransform, true
+	}
+	return nil, false
+}
@@ -1,7 +1,174 @@
 package errors
 
+import (
+	stderrors "errors"
+	"syscall"
+
+	awsretry "github.com/aws/aws-sdk-go-v2/aws/retry"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+	"github.com/mahirjain10/go-workers/internal/message"
+	"github.com/mahirjain10/go-workers/internal/retry"
+	"github.com/mahirjain10/go-workers/internal/transformation"
+	"github.com/mahirjain10/go-workers/internal/validation"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// Code is the stable, machine readable identifier published with a FAILED status
+type Code string
+
+const (
+	CodeDownload          Code = "DOWNLOAD_FAILED"
+	CodeTransform         Code = "TRANSFORM_FAILED"
+	CodeUpload            Code = "UPLOAD_FAILED"
+	CodeDecode            Code = "DECODE_FAILED"
+	CodeUnsupportedFormat Code = "UNSUPPORTED_FORMAT"
+	CodeImageTooLarge     Code = "IMAGE_TOO_LARGE"
+	CodeInvalidParameters Code = "INVALID_PARAMETERS"
+	CodeNotFound          Code = "NOT_FOUND"
+	CodeThrottled         Code = "THROTTLED"
+	CodeAuth              Code = "AUTH_FAILED"
+	CodeDiskFull          Code = "DISK_FULL"
+	CodeCancelled         Code = "CANCELLED"
+	CodeTimeout           Code = "TIMEOUT"
+	CodeExpired           Code = "EXPIRED"
+	CodeInvalidMessage    Code = "INVALID_MESSAGE"
+)
+
+// Error is a job failure with a stable code and a client facing message, Err keeps the underlying cause
+type Error struct {
+	Code Code
+	Msg  string
+	Err  error
+}
+
+func (e *Error) Error() string {
+	if e.Err == nil {
+		return e.Msg
+	}
+	return e.Msg + ": " + e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is matches any *Error with the same code, so errors.Is(err, ErrNotFound) works on wrapped errors
+func (e *Error) Is(target error) bool {
+	var t *Error
+	if !stderrors.As(target, &t) {
+		return false
+	}
+	return e.Code == t.Code
+}
+
+// Wrap attaches cause to a copy of the sentinel
+func Wrap(sentinel *Error, cause error) *Error {
+	return &Error{Code: sentinel.Code, Msg: sentinel.Msg, Err: cause}
+}
+
+// The messages of the first three are what the NestJS side already stores, keep them as is
 var (
-	ErrDownload  = "download failed"
-	ErrTransform = "transform failed"
-	ErrUpload    = "transformed asset upload failed"
+	ErrDownload          = &Error{Code: CodeDownload, Msg: "download failed"}
+	ErrTransform         = &Error{Code: CodeTransform, Msg: "transform failed"}
+	ErrUpload            = &Error{Code: CodeUpload, Msg: "transformed asset upload failed"}
+	ErrDecode            = &Error{Code: CodeDecode, Msg: "image could not be decoded"}
+	ErrUnsupportedFormat = &Error{Code: CodeUnsupportedFormat, Msg: "unsupported or disguised file"}
+	ErrImageTooLarge     = &Error{Code: CodeImageTooLarge, Msg: "image exceeds processing limits"}
+	ErrInvalidParameters = &Error{Code: CodeInvalidParameters, Msg: "invalid transformation parameters"}
+	ErrNotFound          = &Error{Code: CodeNotFound, Msg: "source image not found"}
+	ErrThrottled         = &Error{Code: CodeThrottled, Msg: "storage is throttling requests"}
+	ErrAuth              = &Error{Code: CodeAuth, Msg: "storage authentication failed"}
+	ErrDiskFull          = &Error{Code: CodeDiskFull, Msg: "worker ran out of disk space"}
+	ErrCancelled         = &Error{Code: CodeCancelled, Msg: "job cancelled"}
+	ErrTimeout           = &Error{Code: CodeTimeout, Msg: "job exceeded its deadline"}
+	ErrExpired           = &Error{Code: CodeExpired, Msg: "job expired before it was processed"}
+	ErrInvalidMessage    = &Error{Code: CodeInvalidMessage, Msg: "job message does not match its schema"}
 )
+
+var authCodes = map[string]bool{
+	"AccessDenied":          true,
+	"InvalidAccessKeyId":    true,
+	"SignatureDoesNotMatch": true,
+	"ExpiredToken":          true,
+	"InvalidToken":          true,
+}
+
+// Classify returns the typed error for err. Errors that are already typed are returned as they are,
+// typed errors from validation, transformation, message decoding, the AWS SDK and the OS are mapped to their code and
+// anything else is wrapped in fallback, the error of the step that failed.
+func Classify(err error, fallback *Error) *Error {
+	if classified := classify(err); classified != nil {
+		return classified
+	}
+	return Wrap(fallback, err)
+}
+
+// classify maps err to its typed error, or nil when nothing recognises it
+func classify(err error) *Error {
+	var typed *Error
+	if stderrors.As(err, &typed) {
+		return typed
+	}
+
+	switch {
+	case stderrors.Is(err, validation.ErrUnsupportedFile):
+		return Wrap(ErrUnsupportedFormat, err)
+	case stderrors.Is(err, validation.ErrImageTooLarge):
+		return Wrap(ErrImageTooLarge, err)
+	case stderrors.Is(err, transformation.ErrDecode):
+		return Wrap(ErrDecode, err)
+	case stderrors.Is(err, transformation.ErrInvalidParameters):
+		return Wrap(ErrInvalidParameters, err)
+	case stderrors.Is(err, message.ErrInvalidMessage):
+		return Wrap(ErrInvalidMessage, err)
+	case stderrors.Is(err, syscall.ENOSPC):
+		return Wrap(ErrDiskFull, err)
+	}
+
+	var noSuchKey *s3types.NoSuchKey
+	if stderrors.As(err, &noSuchKey) {
+		return Wrap(ErrNotFound, err)
+	}
+	var apiErr smithy.APIError
+	if stderrors.As(err, &apiErr) {
+		code := apiErr.ErrorCode()
+		if code == "NoSuchKey" || code == "NotFound" {
+			return Wrap(ErrNotFound, err)
+		}
+		if authCodes[code] {
+			return Wrap(ErrAuth, err)
+		}
+		if _, ok := awsretry.DefaultThrottleErrorCodes[code]; ok {
+			return Wrap(ErrThrottled, err)
+		}
+	}
+	var responseErr *awshttp.ResponseError
+	if stderrors.As(err, &responseErr) && responseErr.HTTPStatusCode() == 429 {
+		return Wrap(ErrThrottled, err)
+	}
+	return nil
+}
+
+// IsTransient reports whether retrying the job later may succeed
+func IsTransient(err error) bool {
+	if stderrors.Is(err, ErrThrottled) {
+		return true
+	}
+	return retry.IsRetryable(err)
+}
+
+// IsFatal reports infrastructure failures the worker cannot recover from by itself: a closed
+// RabbitMQ connection or channel, rejected storage credentials or a full disk
+func IsFatal(err error) bool {
+	if stderrors.Is(err, amqp.ErrClosed) {
+		return true
+	}
+	var amqpErr *amqp.Error
+	if stderrors.As(err, &amqpErr) && !amqpErr.Recover {
+		return true
+	}
+	classified := classify(err)
+	return classified != nil && (classified.Code == CodeAuth || classified.Code == CodeDiskFull)
+}
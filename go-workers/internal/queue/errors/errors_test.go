@@ -0,0 +1,123 @@
+package errors
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"net/http"
+	"syscall"
+	"testing"
+
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+	"github.com/mahirjain10/go-workers/internal/message"
+	"github.com/mahirjain10/go-workers/internal/transformation"
+	"github.com/mahirjain10/go-workers/internal/validation"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+func responseError(status int) error {
+	return &awshttp.ResponseError{
+		ResponseError: &smithyhttp.ResponseError{
+			Response: &smithyhttp.Response{Response: &http.Response{StatusCode: status}},
+			Err:      fmt.Errorf("status %d", status),
+		},
+	}
+}
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		fallback *Error
+		want     Code
+	}{
+		{name: "typed error passes through", err: fmt.Errorf("step: %w", Wrap(ErrTimeout, context.DeadlineExceeded)), fallback: ErrTransform, want: CodeTimeout},
+		{name: "unsupported file", err: fmt.Errorf("validate: %w", validation.ErrUnsupportedFile), fallback: ErrDownload, want: CodeUnsupportedFormat},
+		{name: "image too large", err: validation.ErrImageTooLarge, fallback: ErrDownload, want: CodeImageTooLarge},
+		{name: "decode", err: fmt.Errorf("%w: png: bad header", transformation.ErrDecode), fallback: ErrTransform, want: CodeDecode},
+		{name: "invalid parameters", err: transformation.ErrInvalidParameters, fallback: ErrTransform, want: CodeInvalidParameters},
+		{name: "invalid message", err: fmt.Errorf("%w: id is required", message.ErrInvalidMessage), fallback: ErrTransform, want: CodeInvalidMessage},
+		{name: "disk full", err: fmt.Errorf("write: %w", syscall.ENOSPC), fallback: ErrUpload, want: CodeDiskFull},
+		{name: "no such key", err: &s3types.NoSuchKey{}, fallback: ErrDownload, want: CodeNotFound},
+		{name: "head object not found", err: &smithy.GenericAPIError{Code: "NotFound"}, fallback: ErrDownload, want: CodeNotFound},
+		{name: "access denied", err: &smithy.GenericAPIError{Code: "AccessDenied"}, fallback: ErrUpload, want: CodeAuth},
+		{name: "expired token", err: &smithy.GenericAPIError{Code: "ExpiredToken"}, fallback: ErrUpload, want: CodeAuth},
+		{name: "throttle code", err: &smithy.GenericAPIError{Code: "SlowDown"}, fallback: ErrUpload, want: CodeThrottled},
+		{name: "http 429", err: responseError(429), fallback: ErrDownload, want: CodeThrottled},
+		{name: "http 500 falls back", err: responseError(500), fallback: ErrDownload, want: CodeDownload},
+		{name: "unknown error falls back", err: stderrors.New("boom"), fallback: ErrUpload, want: CodeUpload},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Classify(tt.err, tt.fallback)
+			if got.Code != tt.want {
+				t.Fatalf("Classify(%v) = %s, want %s", tt.err, got.Code, tt.want)
+			}
+			if !stderrors.Is(got, tt.err) {
+				t.Fatalf("Classify(%v) lost the cause", tt.err)
+			}
+		})
+	}
+}
+
+func TestErrorIsMatchesCode(t *testing.T) {
+	err := fmt.Errorf("process: %w", Wrap(ErrNotFound, stderrors.New("missing")))
+	if !stderrors.Is(err, ErrNotFound) {
+		t.Fatal("wrapped error does not match its sentinel")
+	}
+	if stderrors.Is(err, ErrDownload) {
+		t.Fatal("wrapped error matches a sentinel with another code")
+	}
+	if got, want := Wrap(ErrNotFound, stderrors.New("missing")).Error(), "source image not found: missing"; got != want {
+		t.Fatalf("Error() = %q, want %q", got, want)
+	}
+}
+
+func TestIsTransient(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "throttled", err: Wrap(ErrThrottled, stderrors.New("slow down")), want: true},
+		{name: "connection reset", err: Wrap(ErrDownload, syscall.ECONNRESET), want: true},
+		{name: "server error", err: Wrap(ErrUpload, responseError(503)), want: true},
+		{name: "not found", err: Wrap(ErrNotFound, &s3types.NoSuchKey{}), want: false},
+		{name: "decode", err: Wrap(ErrDecode, transformation.ErrDecode), want: false},
+		{name: "cancelled", err: Wrap(ErrCancelled, context.Canceled), want: false},
+		{name: "unknown", err: stderrors.New("boom"), want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsTransient(tt.err); got != tt.want {
+				t.Fatalf("IsTransient(%v) = %t, want %t", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsFatal(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "closed connection", err: fmt.Errorf("publish: %w", amqp.ErrClosed), want: true},
+		{name: "unrecoverable amqp error", err: &amqp.Error{Code: amqp.ChannelError, Recover: false}, want: true},
+		{name: "recoverable amqp error", err: &amqp.Error{Code: amqp.ContentTooLarge, Recover: true}, want: false},
+		{name: "rejected credentials", err: &smithy.GenericAPIError{Code: "InvalidAccessKeyId"}, want: true},
+		{name: "disk full", err: fmt.Errorf("write: %w", syscall.ENOSPC), want: true},
+		{name: "not found", err: &s3types.NoSuchKey{}, want: false},
+		{name: "connection reset", err: syscall.ECONNRESET, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsFatal(tt.err); got != tt.want {
+				t.Fatalf("IsFatal(%v) = %t, want %t", tt.err, got, tt.want)
+			}
+		})
+	}
+}
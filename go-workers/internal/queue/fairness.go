@@ -0,0 +1,59 @@
+package queue
+
+import (
+	"context"
+	"log"
+
+	"github.com/mahirjain10/go-workers/internal/types"
+	"github.com/mahirjain10/go-workers/internal/utils"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// admitUser applies the per user limits before a job runs. Over the limits the job goes to the queue's
+// deferred TTL queue, which hands it back to the work queue after UserDeferDelay, and the original is
+// acked. It returns false once d was deferred, otherwise release must be called when the job is done.
+func (rabbitMqService *RabbitMqService) admitUser(ctx context.Context, ch *amqp.Channel, queueName string, d amqp.Delivery, job types.ImageProcessing) (release func(), admitted bool) {
+	if job.UserId == "" {
+		return func() {}, true
+	}
+	if release, ok := rabbitMqService.userLimiter.Admit(job.UserId); ok {
+		return release, true
+	}
+
+	headers := amqp.Table{}
+	for key, value := range d.Headers {
+		headers[key] = value
+	}
+	deferred := deferCount(d) + 1
+	headers[utils.HeaderDeferred] = int32(deferred)
+
+	deferQueue := utils.DeferQueueName(queueName, rabbitMqService.config.UserDeferDelay)
+	// The original is acked below, so the deferred copy must be confirmed first
+	err := utils.PublishConfirmed(ctx, ch, "", deferQueue, amqp.Publishing{
+		ContentType:  d.ContentType,
+		DeliveryMode: amqp.Persistent,
+		Priority:     d.Priority,
+		Headers:      headers,
+		MessageId:    d.MessageId,
+		Body:         d.Body,
+	})
+	if err != nil {
+		// Requeueing would spin on the same message, running it over the limit is the lesser evil
+		log.Printf("[%s] failed to defer job %s of user %s, processing it now: %v", queueName, job.Id, job.UserId, err)
+		return func() {}, true
+	}
+	log.Printf("[%s] user %s is over its limits, job %s deferred (%d times)", queueName, job.UserId, job.Id, deferred)
+	d.Ack(false)
+	return nil, false
+}
+
+func deferCount(d amqp.Delivery) int {
+	switch deferred := d.Headers[utils.HeaderDeferred].(type) {
+	case int32:
+		return int(deferred)
+	case int64:
+		return int(deferred)
+	default:
+		return 0
+	}
+}
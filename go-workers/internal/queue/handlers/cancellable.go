@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mahirjain10/go-workers/internal/metrics"
+)
+
+// runCancellable runs CPU-bound work that cannot watch ctx itself, imaging has no cancellation points.
+// It returns with the cause of ctx as soon as ctx is done while the work carries on in the background:
+// release only runs once fn returns, even when it panics, so the memory budget keeps counting an
+// abandoned decode until its memory can actually be reclaimed. Abandoned work is counted in metrics until
+// it returns, the consumers stop taking jobs while too much of it piles up.
+func runCancellable[T any](ctx context.Context, release func(), fn func() (T, error)) (T, error) {
+	type outcome struct {
+		value T
+		err   error
+	}
+	done := make(chan outcome, 1)
+	// The goroutine owns release, callers must not release the budget themselves. It releases before
+	// handing the outcome over, so a caller that got its result never finds the budget still taken.
+	go func() {
+		var result outcome
+		defer func() {
+			release()
+			done <- result
+		}()
+		result.value, result.err = fn()
+	}()
+
+	select {
+	case result := <-done:
+		return result.value, result.err
+	case <-ctx.Done():
+		finished := metrics.TransformationAbandoned()
+		go func() {
+			<-done
+			finished()
+		}()
+		var zero T
+		return zero, fmt.Errorf("transformation abandoned: %w", context.Cause(ctx))
+	}
+}
@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mahirjain10/go-workers/internal/admission"
+)
+
+var errDeadline = errors.New("deadline")
+
+func TestRunCancellableReturnsResult(t *testing.T) {
+	released := 0
+	value, err := runCancellable(context.Background(), func() { released++ }, func() (int, error) {
+		return 42, nil
+	})
+	if value != 42 || err != nil {
+		t.Fatalf("runCancellable() = %d, %v, want 42, nil", value, err)
+	}
+	if released != 1 {
+		t.Fatalf("release ran %d times, want once", released)
+	}
+}
+
+func TestRunCancellableHoldsBudgetUntilAbandonedWorkReturns(t *testing.T) {
+	budget := admission.NewMemoryBudget(100)
+	release, err := budget.Acquire(context.Background(), 100)
+	if err != nil {
+		t.Fatalf("Acquire() = %v", err)
+	}
+
+	ctx, cancel := context.WithCancelCause(context.Background())
+	unblock := make(chan struct{})
+	returned := make(chan struct{})
+	go func() {
+		defer close(returned)
+		_, err := runCancellable(ctx, release, func() (int, error) {
+			<-unblock
+			return 0, nil
+		})
+		if !errors.Is(err, errDeadline) {
+			t.Errorf("runCancellable() = %v, want the cause of ctx", err)
+		}
+	}()
+	cancel(errDeadline)
+	<-returned
+
+	// The abandoned work still holds the whole budget
+	waitCtx, cancelWait := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancelWait()
+	if _, err := budget.Acquire(waitCtx, 1); !errors.Is(err, admission.ErrBudgetUnavailable) {
+		t.Fatalf("Acquire() = %v while abandoned work held the budget, want ErrBudgetUnavailable", err)
+	}
+
+	close(unblock)
+	acquireCtx, cancelAcquire := context.WithTimeout(context.Background(), time.Second)
+	defer cancelAcquire()
+	next, err := budget.Acquire(acquireCtx, 100)
+	if err != nil {
+		t.Fatalf("Acquire() = %v once the abandoned work returned", err)
+	}
+	next()
+}
@@ -1,117 +1,232 @@
 package handlers
 
 import (
+	"context"
 	"fmt"
 	"log"
+	"os"
 	"path"
 	"strings"
 
+	"github.com/mahirjain10/go-workers/config"
+	"github.com/mahirjain10/go-workers/internal/admission"
 	"github.com/mahirjain10/go-workers/internal/aws"
+	"github.com/mahirjain10/go-workers/internal/message"
+	"github.com/mahirjain10/go-workers/internal/placeholder"
+	queueErrors "github.com/mahirjain10/go-workers/internal/queue/errors"
 	"github.com/mahirjain10/go-workers/internal/transformation"
 	"github.com/mahirjain10/go-workers/internal/types"
 	"github.com/mahirjain10/go-workers/internal/utils"
+	"github.com/mahirjain10/go-workers/internal/validation"
 )
 
 type TransformHandler struct {
-	s3Service *aws.S3Service
+	s3Service       *aws.S3Service
+	inspectDefaults types.Inspect
+	limits          validation.Limits
+	memoryBudget    *admission.MemoryBudget
 }
 
-func NewTransformHandler(s3Service *aws.S3Service) *TransformHandler {
+func NewTransformHandler(s3Service *aws.S3Service, config *config.Config) *TransformHandler {
 	return &TransformHandler{
-		s3Service: s3Service,
+		s3Service:       s3Service,
+		inspectDefaults: config.InspectDefaults,
+		limits:          config.ImageLimits,
+		memoryBudget:    admission.NewMemoryBudget(config.MemoryBudget),
 	}
 }
 
-func (h *TransformHandler) TransformImage(imageProcessing types.ImageProcessing) error {
-	_, downloadPath, uploadPath := h.s3Service.GetDependencyData()
+// readRawImage reads the downloaded raw file, checks its magic bytes and enforces the decode limits
+// before anything tries to decode it. It then waits until the estimated decoded size fits in the
+// memory budget, the caller hands release to runCancellable so it runs once the decode is done. A
+// wait cut short by the job deadline fails with admission.ErrBudgetUnavailable and is requeued.
+func (h *TransformHandler) readRawImage(ctx context.Context, imageProcessing types.ImageProcessing) ([]byte, func(), error) {
+	_, downloadPath, _ := h.s3Service.GetDependencyData()
+	return h.readImage(ctx, downloadPath, imageProcessing.S3RawKey)
+}
+
+// readImage is readRawImage for any file under basePath, key names the file and its expected format
+func (h *TransformHandler) readImage(ctx context.Context, basePath string, key string) ([]byte, func(), error) {
 	// Prepare a download path
-	updatedDownloadPath, err := utils.PathUtil(downloadPath, imageProcessing.S3RawKey)
+	updatedDownloadPath, err := utils.PathUtil(basePath, key)
+	if err != nil {
+		return nil, nil, err
+	}
+	// Refuse oversized files before reading them into memory
+	fileInfo, err := os.Stat(updatedDownloadPath)
 	if err != nil {
-		return err
+		return nil, nil, fmt.Errorf("error while reading image :%v", err)
+	}
+	if err := h.limits.CheckSize(fileInfo.Size()); err != nil {
+		return nil, nil, err
 	}
 	// Read image buffer from the download path
 	imageBuffer, err := utils.ReadImageBuffer(updatedDownloadPath)
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
+	format, err := validation.ValidateImage(imageBuffer, key)
+	if err != nil {
+		return nil, nil, err
+	}
+	// Only the header is decoded here, the pixels are decoded by the transformation once this passes
+	imageConfig, err := h.limits.CheckImage(imageBuffer, format)
+	if err != nil {
+		return nil, nil, err
+	}
+	release, err := h.memoryBudget.Acquire(ctx, admission.EstimateDecodedSize(imageConfig))
+	if err != nil {
+		return nil, nil, err
+	}
+	return imageBuffer, release, nil
+}
 
-	var transformedImageBytes []byte
-	var formatToConvert = ""
-	switch imageProcessing.TransformationType {
-	case "RESIZE":
-		var resize *types.Resize
-		if err := utils.ParseJSON([]byte(imageProcessing.TransformationParameters), &resize); err != nil {
-			return fmt.Errorf("failed to parse message: %w", err)
-		}
-		transformedImageBytes, err = transformation.Resize(imageBuffer, resize.Height, resize.Width)
-		if err != nil {
-			return err
-		}
-
-	case "ROTATE":
-		var rotate *types.Rotate
-		if err := utils.ParseJSON([]byte(imageProcessing.TransformationParameters), &rotate); err != nil {
-			return fmt.Errorf("failed to parse message: %w", err)
-		}
-		transformedImageBytes, err = transformation.Rotate(imageBuffer, rotate.Degree)
-		if err != nil {
-			return err
-		}
+// Placeholders computes the BlurHash and LQIP of a processed file. Decoding the output is held to the same
+// limits and memory budget as decoding the raw image.
+func (h *TransformHandler) Placeholders(ctx context.Context, processedKey string) (string, string, error) {
+	_, _, uploadPath := h.s3Service.GetDependencyData()
+	imageBuffer, release, err := h.readImage(ctx, uploadPath, processedKey)
+	if err != nil {
+		return "", "", err
+	}
+	placeholders, err := runCancellable(ctx, release, func() ([2]string, error) {
+		blurHash, lqip, err := placeholder.Generate(imageBuffer)
+		return [2]string{blurHash, lqip}, err
+	})
+	if err != nil {
+		return "", "", err
+	}
+	return placeholders[0], placeholders[1], nil
+}
 
-	case "CONVERT":
-		var convert *types.Convert
-		if err := utils.ParseJSON([]byte(imageProcessing.TransformationParameters), &convert); err != nil {
-			return fmt.Errorf("failed to parse message: %w", err)
-		}
-		formatToConvert = strings.ToLower(convert.Format)
-		transformedImageBytes, err = transformation.Convert(imageBuffer, convert.Format)
-		if err != nil {
-			return err
-		}
+// InspectImage runs the quality checks on the downloaded raw image, nothing is written for upload
+func (h *TransformHandler) InspectImage(ctx context.Context, imageProcessing types.ImageProcessing) (*types.InspectionResult, error) {
+	inspect, err := message.ParseInspect(imageProcessing.TransformationParameters)
+	if err != nil {
+		return nil, err
+	}
+	thresholds := inspect.Thresholds(h.inspectDefaults)
 
-	case "FORCE_RESIZE":
-		var resize *types.Resize
-		if err := utils.ParseJSON([]byte(imageProcessing.TransformationParameters), &resize); err != nil {
-			return fmt.Errorf("failed to parse message: %w", err)
-		}
-		transformedImageBytes, err = transformation.ForceResize(imageBuffer, resize.Height, resize.Width)
-		if err != nil {
-			return err
-		}
+	imageBuffer, release, err := h.readRawImage(ctx, imageProcessing)
+	if err != nil {
+		return nil, err
+	}
+	return runCancellable(ctx, release, func() (*types.InspectionResult, error) {
+		return transformation.Inspect(imageBuffer, thresholds)
+	})
+}
 
-	default:
-		return fmt.Errorf("unsupported transformation type: %s", imageProcessing.TransformationType)
+// HistogramImage computes the histograms of the downloaded raw image. When a render is requested the PNG
+// is written next to the other processed files and its key (relative to the upload path) is returned.
+func (h *TransformHandler) HistogramImage(ctx context.Context, imageProcessing types.ImageProcessing) (*types.HistogramResult, string, error) {
+	_, _, uploadPath := h.s3Service.GetDependencyData()
+	histogram, err := message.ParseHistogram(imageProcessing.TransformationParameters)
+	if err != nil {
+		return nil, "", err
 	}
 
-	log.Println("split looks like ", strings.Split(imageProcessing.S3RawKey, "/"))
+	imageBuffer, release, err := h.readRawImage(ctx, imageProcessing)
+	if err != nil {
+		return nil, "", err
+	}
+	type rendered struct {
+		result *types.HistogramResult
+		png    []byte
+	}
+	output, err := runCancellable(ctx, release, func() (rendered, error) {
+		result, err := transformation.Histogram(imageBuffer)
+		if err != nil || !histogram.Render {
+			return rendered{result: result}, err
+		}
+		png, err := transformation.RenderHistogram(result)
+		return rendered{result: result, png: png}, err
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	result, renderedBytes := output.result, output.png
+	if !histogram.Render {
+		return result, "", nil
+	}
 
-	var finalKey string
-	// Get the s3key and separate the "raw/"
 	parts := strings.Split(imageProcessing.S3RawKey, "/")
 	if len(parts) < 2 {
-		return fmt.Errorf("unexpected S3RawKey format: %s", imageProcessing.S3RawKey)
+		return nil, "", fmt.Errorf("unexpected S3RawKey format: %s", imageProcessing.S3RawKey)
 	}
-	finalKey = parts[1]
-	if imageProcessing.TransformationType == "CONVERT" {
-		dotSplit := strings.Split(parts[1], ".")
-		if len(dotSplit) < 2 {
-			return fmt.Errorf("unexpected S3RawKey format: %s", imageProcessing.S3RawKey)
-		}
-		finalKey = fmt.Sprintf("%s.%s", dotSplit[0], formatToConvert)
+	base := strings.TrimSuffix(parts[1], path.Ext(parts[1]))
+	renderedKey := fmt.Sprintf("processed/%s_histogram.png", base)
+
+	renderedPath, err := utils.PathUtil(uploadPath, renderedKey)
+	if err != nil {
+		return nil, "", err
+	}
+	if err := utils.WriteImageBuffer(renderedPath, renderedBytes); err != nil {
+		return nil, "", err
+	}
+	return result, renderedKey, nil
+}
+
+// TransformImage applies the job's registered Transformer to the downloaded raw image and writes the result
+// next to the other processed files. It returns the processed key, relative to the upload path, to upload.
+func (h *TransformHandler) TransformImage(ctx context.Context, imageProcessing types.ImageProcessing) (string, error) {
+	_, _, uploadPath := h.s3Service.GetDependencyData()
+	transformer, ok := transformation.Lookup(imageProcessing.TransformationType)
+	if !ok {
+		return "", queueErrors.Wrap(queueErrors.ErrInvalidParameters, fmt.Errorf("unsupported transformation type: %s", imageProcessing.TransformationType))
+	}
+	// Parameters are checked before the image is read, so a bad job never holds the memory budget
+	operation, err := transformer.Parse(imageProcessing.TransformationParameters)
+	if err != nil {
+		return "", err
+	}
+	if err := operation.Validate(h.limits); err != nil {
+		return "", err
+	}
+	processedKey, err := ProcessedKey(imageProcessing.S3RawKey, operation.OutputExtension())
+	if err != nil {
+		return "", err
+	}
+
+	// Read and validate the downloaded raw image
+	imageBuffer, release, err := h.readRawImage(ctx, imageProcessing)
+	if err != nil {
+		return "", err
+	}
+	transformedImageBytes, err := runCancellable(ctx, release, func() ([]byte, error) {
+		return operation.Apply(imageBuffer)
+	})
+	if err != nil {
+		return "", err
 	}
 
 	// Format the upload path where it saves the image and from where images can be uploaded
-	formattedUploadPath, err := utils.PathUtil(path.Join(uploadPath, "processed"), finalKey)
+	formattedUploadPath, err := utils.PathUtil(uploadPath, processedKey)
 	if err != nil {
-		return err
+		return "", err
 	}
 	log.Printf("formatted path :%s", formattedUploadPath)
 
 	// Write image buffer to the path from where it would be uploaded to s3
-	err = utils.WriteImageBuffer(formattedUploadPath, transformedImageBytes)
-	if err != nil {
-		return err
+	if err := utils.WriteImageBuffer(formattedUploadPath, transformedImageBytes); err != nil {
+		return "", err
 	}
+	return processedKey, nil
+}
 
-	return nil
+// ProcessedKey maps raw/<name> to processed/<name>, swapping the extension when the output has its own
+func ProcessedKey(s3RawKey string, extension string) (string, error) {
+	// Get the s3key and separate the "raw/"
+	parts := strings.Split(s3RawKey, "/")
+	if len(parts) < 2 {
+		return "", queueErrors.Wrap(queueErrors.ErrUpload, fmt.Errorf("unexpected S3RawKey format: %s", s3RawKey))
+	}
+	finalKey := parts[1]
+	if extension != "" {
+		sourceExtension := path.Ext(finalKey)
+		if sourceExtension == "" {
+			return "", queueErrors.Wrap(queueErrors.ErrUpload, fmt.Errorf("unexpected S3RawKey format: %s", s3RawKey))
+		}
+		finalKey = strings.TrimSuffix(finalKey, sourceExtension) + "." + extension
+	}
+	return "processed/" + finalKey, nil
 }
@@ -7,18 +7,22 @@ import (
 	amqp "github.com/rabbitmq/amqp091-go"
 )
 
+// ProcessingError is a failed delivery. Requeue asks for another attempt while retry tiers remain. Job is
+// set once the message decoded: its FAILED status and the Cleanup mode only run when the delivery is dead
+// lettered, a job that is retried keeps its raw object.
 type ProcessingError struct {
 	Err     error
 	Requeue bool
+	Job     *types.ImageProcessing
+	Cleanup string
 }
 
 func (p ProcessingError) Error() string {
 	return p.Err.Error()
 }
 
-type RabbitMqMessage struct {
-	Pattern string
-	Data    types.ImageProcessing
+func (p ProcessingError) Unwrap() error {
+	return p.Err
 }
 
 type RabbitMqService struct {
@@ -0,0 +1,72 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+)
+
+// workerPool runs the consumer goroutines of one queue and resizes them at runtime. Each consumer has its
+// own stop context, scaling down cancels the newest ones, which finish their in-flight job and exit.
+type workerPool struct {
+	queueName string
+	ctx       context.Context
+	// run starts one consumer in its own goroutine, it stops once stop is done
+	run func(stop context.Context, consumerTag string)
+
+	mu    sync.Mutex
+	stops []context.CancelFunc
+	next  int
+	// inFlight counts the deliveries the consumers received and did not settle yet
+	inFlight atomic.Int64
+}
+
+func newWorkerPool(ctx context.Context, queueName string, run func(stop context.Context, consumerTag string)) *workerPool {
+	return &workerPool{
+		queueName: queueName,
+		ctx:       ctx,
+		run:       run,
+	}
+}
+
+// scale starts or stops consumers until count are running
+func (p *workerPool) scale(count int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	// Once shutting down the drain owns the consumers
+	if count == len(p.stops) || p.ctx.Err() != nil {
+		return
+	}
+	log.Printf("[%s] scaling workers from %d to %d", p.queueName, len(p.stops), count)
+	for len(p.stops) < count {
+		p.next++
+		stop, cancel := context.WithCancel(p.ctx)
+		p.stops = append(p.stops, cancel)
+		p.run(stop, fmt.Sprintf("%s-worker-%d", p.queueName, p.next))
+	}
+	for len(p.stops) > count {
+		last := len(p.stops) - 1
+		p.stops[last]()
+		p.stops = p.stops[:last]
+	}
+}
+
+func (p *workerPool) size() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.stops)
+}
+
+// working counts a delivery as in flight until the returned func is called
+func (p *workerPool) working() func() {
+	p.inFlight.Add(1)
+	return func() { p.inFlight.Add(-1) }
+}
+
+// busy returns the number of deliveries in flight
+func (p *workerPool) busy() int {
+	return int(p.inFlight.Load())
+}
@@ -0,0 +1,87 @@
+package queue
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+// startedConsumer is a consumer a test pool started
+type startedConsumer struct {
+	tag  string
+	stop context.Context
+}
+
+func newTestPool(ctx context.Context) (*workerPool, *[]startedConsumer) {
+	started := &[]startedConsumer{}
+	pool := newWorkerPool(ctx, "resize_queue", func(stop context.Context, consumerTag string) {
+		*started = append(*started, startedConsumer{tag: consumerTag, stop: stop})
+	})
+	return pool, started
+}
+
+// running returns the tags of the started consumers that were not stopped
+func running(started []startedConsumer) []string {
+	var tags []string
+	for _, consumer := range started {
+		if consumer.stop.Err() == nil {
+			tags = append(tags, consumer.tag)
+		}
+	}
+	return tags
+}
+
+func TestWorkerPoolScale(t *testing.T) {
+	tests := []struct {
+		name   string
+		counts []int
+		want   []string
+	}{
+		{name: "scale up", counts: []int{3}, want: []string{"resize_queue-worker-1", "resize_queue-worker-2", "resize_queue-worker-3"}},
+		{name: "scale down stops the newest", counts: []int{3, 1}, want: []string{"resize_queue-worker-1"}},
+		{name: "scale to zero", counts: []int{2, 0}},
+		{name: "tags are not reused", counts: []int{2, 1, 2}, want: []string{"resize_queue-worker-1", "resize_queue-worker-3"}},
+		{name: "same count is a no-op", counts: []int{2, 2}, want: []string{"resize_queue-worker-1", "resize_queue-worker-2"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pool, started := newTestPool(context.Background())
+			for _, count := range tt.counts {
+				pool.scale(count)
+			}
+			if got := running(*started); !slices.Equal(got, tt.want) {
+				t.Fatalf("running consumers = %q, want %q", got, tt.want)
+			}
+			if pool.size() != len(tt.want) {
+				t.Fatalf("size() = %d, want %d", pool.size(), len(tt.want))
+			}
+		})
+	}
+}
+
+func TestWorkerPoolScaleAfterShutdown(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	pool, started := newTestPool(ctx)
+	pool.scale(2)
+	cancel()
+
+	// The drain owns the consumers once ctx is done
+	pool.scale(5)
+	if len(*started) != 2 || pool.size() != 2 {
+		t.Fatalf("scale after shutdown started %d consumers, size %d", len(*started), pool.size())
+	}
+}
+
+func TestWorkerPoolBusy(t *testing.T) {
+	pool, _ := newTestPool(context.Background())
+	first := pool.working()
+	second := pool.working()
+	if pool.busy() != 2 {
+		t.Fatalf("busy() = %d, want 2", pool.busy())
+	}
+	first()
+	second()
+	if pool.busy() != 0 {
+		t.Fatalf("busy() = %d, want 0", pool.busy())
+	}
+}
@@ -0,0 +1,41 @@
+package queue
+
+import (
+	"context"
+	"log"
+
+	"github.com/mahirjain10/go-workers/internal/types"
+	"github.com/mahirjain10/go-workers/internal/utils"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// promotePriority honors the priority field of job when its producer did not set the AMQP priority.
+// It publishes d again on its queue with the priority set, so the broker moves it ahead of lower
+// priority jobs, and acks the original. It reports whether d was promoted.
+func (rabbitMqService *RabbitMqService) promotePriority(ctx context.Context, ch *amqp.Channel, queueName string, d amqp.Delivery, job types.ImageProcessing) bool {
+	maxPriority := rabbitMqService.config.QueueMaxPriority
+	if maxPriority == 0 {
+		return false
+	}
+	priority := utils.ClampPriority(job.Priority, maxPriority)
+	if priority <= d.Priority {
+		return false
+	}
+
+	// The original is acked below, so the promoted copy must be confirmed first
+	err := utils.PublishConfirmed(ctx, ch, "", queueName, amqp.Publishing{
+		ContentType:  d.ContentType,
+		DeliveryMode: amqp.Persistent,
+		Priority:     priority,
+		Headers:      d.Headers,
+		MessageId:    d.MessageId,
+		Body:         d.Body,
+	})
+	if err != nil {
+		log.Printf("[%s] failed to promote message to priority %d, processing it now: %v", queueName, priority, err)
+		return false
+	}
+	log.Printf("[%s] message promoted from priority %d to %d", queueName, d.Priority, priority)
+	d.Ack(false)
+	return true
+}
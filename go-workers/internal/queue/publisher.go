@@ -0,0 +1,247 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/mahirjain10/go-workers/internal/outbox"
+	"github.com/mahirjain10/go-workers/internal/retry"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// confirmTimeout bounds how long a publish waits for the broker's ack before the relay may retry it
+const confirmTimeout = 30 * time.Second
+
+// latestRetention is how long the outbox remembers the newest message of a job after it was confirmed
+const latestRetention = time.Hour
+
+// returnPolicy spaces out the retries of unroutable messages, one returned MaxAttempts times is dropped
+var returnPolicy = retry.Policy{MaxAttempts: 10, BaseDelay: 5 * time.Second, MaxDelay: 5 * time.Minute, Multiplier: 2}
+
+// Headers that let a returned message be put back in the outbox as it was
+const (
+	headerJobID   = "x-job-id"
+	headerReturns = "x-returns"
+)
+
+// statusPublisher publishes status messages reliably: every message goes through the outbox, is published
+// in confirm mode with mandatory set, and leaves the outbox only once the broker acked it. Nacked and
+// unconfirmed messages stay in the outbox and the relay publishes them again, returned (unroutable) ones
+// are retried with a backoff. A newer status of a job supersedes its pending older ones.
+type statusPublisher struct {
+	outbox *outbox.Outbox
+
+	mu sync.Mutex
+	ch *amqp.Channel
+	// inFlight holds the outbox ids published on ch and still waiting for their confirm
+	inFlight map[uint64]bool
+	// returned holds the outbox ids put back by handleReturns, their ack must not remove them
+	returned map[uint64]bool
+}
+
+func newStatusPublisher(outbox *outbox.Outbox) *statusPublisher {
+	return &statusPublisher{
+		outbox:   outbox,
+		inFlight: make(map[uint64]bool),
+		returned: make(map[uint64]bool),
+	}
+}
+
+// attach puts ch in confirm mode and publishes on it from now on
+func (p *statusPublisher) attach(ch *amqp.Channel) error {
+	if err := ch.Confirm(false); err != nil {
+		return fmt.Errorf("failed to enable publisher confirms: %w", err)
+	}
+	returns := ch.NotifyReturn(make(chan amqp.Return, 16))
+	go p.handleReturns(returns)
+
+	p.mu.Lock()
+	p.ch = ch
+	p.inFlight = make(map[uint64]bool)
+	p.returned = make(map[uint64]bool)
+	p.mu.Unlock()
+	return nil
+}
+
+// handleReturns puts unroutable messages back in the outbox under their original id, to be sent again
+// after a backoff. The broker still acks a returned message, returned tells awaitConfirm to keep it; when
+// the ack was handled first the entry is simply written again. Messages superseded by a newer status of
+// their job, or returned returnPolicy.MaxAttempts times, are dropped.
+func (p *statusPublisher) handleReturns(returns <-chan amqp.Return) {
+	for r := range returns {
+		id, err := strconv.ParseUint(r.MessageId, 10, 64)
+		if err != nil {
+			log.Printf("[publisher] returned message %q is not from the outbox, dropping it", r.MessageId)
+			continue
+		}
+		jobID, _ := r.Headers[headerJobID].(string)
+		returned := returnCount(r.Headers) + 1
+		if returned >= returnPolicy.MaxAttempts {
+			log.Printf("[publisher] message %d of job %s returned %d times: %d %s, dropping it", id, jobID, returned, r.ReplyCode, r.ReplyText)
+			continue
+		}
+		entry := outbox.Entry{
+			ID:          id,
+			JobID:       jobID,
+			Exchange:    r.Exchange,
+			RoutingKey:  r.RoutingKey,
+			ContentType: r.ContentType,
+			Body:        r.Body,
+			CreatedAt:   time.Now().UTC(),
+			Returns:     returned,
+			NotBefore:   time.Now().Add(returnPolicy.Backoff(returned)),
+		}
+
+		p.mu.Lock()
+		restored, err := p.outbox.Restore(entry)
+		if restored {
+			p.returned[id] = true
+		}
+		p.mu.Unlock()
+		switch {
+		case err != nil:
+			log.Printf("[publisher] failed to keep returned message %d: %v", id, err)
+		case !restored:
+			log.Printf("[publisher] message %d returned by broker but superseded, dropping it", id)
+		default:
+			log.Printf("[publisher] message %d returned by broker: %d %s, retrying after %s", id, r.ReplyCode, r.ReplyText, returnPolicy.Backoff(returned))
+		}
+	}
+}
+
+func returnCount(headers amqp.Table) int {
+	switch returned := headers[headerReturns].(type) {
+	case int32:
+		return int(returned)
+	case int64:
+		return int(returned)
+	default:
+		return 0
+	}
+}
+
+// publish stores the message in the outbox and publishes it. Once the outbox accepted the message
+// a failing publish is not an error, the relay will publish it again. Adding and sending under p.mu keeps
+// the relay from sending an entry this one superseded after it.
+func (p *statusPublisher) publish(ctx context.Context, jobID string, exchange string, routingKey string, contentType string, body []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	entry, err := p.outbox.Add(jobID, exchange, routingKey, contentType, body)
+	if err != nil {
+		return err
+	}
+	if err := p.send(ctx, entry); err != nil {
+		log.Printf("[publisher] publish of message %d failed, left in outbox: %v", entry.ID, err)
+	}
+	return nil
+}
+
+// resend publishes an entry the relay read from the outbox, unless it was confirmed or superseded since
+func (p *statusPublisher) resend(ctx context.Context, entry outbox.Entry) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	pending, err := p.outbox.Contains(entry.ID)
+	if err != nil || !pending {
+		return err
+	}
+	return p.send(ctx, entry)
+}
+
+// send publishes one outbox entry and removes it in the background once confirmed, p.mu must be held
+func (p *statusPublisher) send(ctx context.Context, entry outbox.Entry) error {
+	if p.ch == nil || p.ch.IsClosed() {
+		return fmt.Errorf("status channel is not available")
+	}
+	if p.inFlight[entry.ID] {
+		return nil
+	}
+	delete(p.returned, entry.ID)
+
+	confirmation, err := p.ch.PublishWithDeferredConfirmWithContext(ctx,
+		entry.Exchange,
+		entry.RoutingKey,
+		true,
+		false,
+		amqp.Publishing{
+			ContentType:  entry.ContentType,
+			DeliveryMode: amqp.Persistent,
+			MessageId:    strconv.FormatUint(entry.ID, 10),
+			Headers:      amqp.Table{headerJobID: entry.JobID, headerReturns: int32(entry.Returns)},
+			Body:         entry.Body,
+		})
+	if err != nil {
+		return fmt.Errorf("failed to publish message: %w", err)
+	}
+	p.inFlight[entry.ID] = true
+	go p.awaitConfirm(p.ch, confirmation, entry.ID)
+	return nil
+}
+
+func (p *statusPublisher) awaitConfirm(ch *amqp.Channel, confirmation *amqp.DeferredConfirmation, id uint64) {
+	ctx, cancel := context.WithTimeout(context.Background(), confirmTimeout)
+	defer cancel()
+
+	acked, err := confirmation.WaitContext(ctx)
+	switch {
+	case err != nil:
+		log.Printf("[publisher] no confirm for message %d, will retry: %v", id, err)
+	case !acked:
+		log.Printf("[publisher] message %d nacked by broker, will retry", id)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err == nil && acked {
+		if p.returned[id] {
+			delete(p.returned, id)
+		} else if err := p.outbox.Remove(id); err != nil {
+			log.Printf("[publisher] %v", err)
+		}
+	}
+	if p.ch == ch {
+		delete(p.inFlight, id)
+	}
+}
+
+// relay publishes whatever is left in the outbox every interval until ctx is done
+func (p *statusPublisher) relay(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	var lastPruned time.Time
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if time.Since(lastPruned) >= latestRetention/4 {
+			if err := p.outbox.Prune(latestRetention); err != nil {
+				log.Printf("[publisher] %v", err)
+			}
+			lastPruned = time.Now()
+		}
+		entries, err := p.outbox.Pending()
+		if err != nil {
+			log.Printf("[publisher] %v", err)
+			continue
+		}
+		now := time.Now()
+		for _, entry := range entries {
+			if now.Before(entry.NotBefore) {
+				continue
+			}
+			if err := p.resend(ctx, entry); err != nil {
+				log.Printf("[publisher] retry of message %d failed: %v", entry.ID, err)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package queue
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/mahirjain10/go-workers/internal/outbox"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+func TestResendSkipsSupersededMessages(t *testing.T) {
+	o, err := outbox.Open(filepath.Join(t.TempDir(), "outbox.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer o.Close()
+	publisher := newStatusPublisher(o)
+	ctx := context.Background()
+
+	// No channel is attached, so every publish is left in the outbox
+	if err := publisher.publish(ctx, "job-1", "status", "status", "application/json", []byte("PROCESSING")); err != nil {
+		t.Fatal(err)
+	}
+	pending, err := o.Pending()
+	if err != nil || len(pending) != 1 {
+		t.Fatalf("Pending() = %v, %v, want the PROCESSING message", pending, err)
+	}
+	processing := pending[0]
+	if err := publisher.publish(ctx, "job-1", "status", "status", "application/json", []byte("PROCESSED")); err != nil {
+		t.Fatal(err)
+	}
+
+	// The relay read PROCESSING before PROCESSED superseded it, it must not be sent after PROCESSED
+	if err := publisher.resend(ctx, processing); err != nil {
+		t.Fatalf("resend() of a superseded message = %v, want it skipped", err)
+	}
+	pending, err = o.Pending()
+	if err != nil || len(pending) != 1 || string(pending[0].Body) != "PROCESSED" {
+		t.Fatalf("Pending() = %v, %v, want only PROCESSED", pending, err)
+	}
+	if err := publisher.resend(ctx, pending[0]); err == nil {
+		t.Fatal("resend() of a pending message without a channel succeeded")
+	}
+}
+
+func TestReturnCount(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers amqp.Table
+		want    int
+	}{
+		{name: "never returned", headers: amqp.Table{}},
+		{name: "int32 as published", headers: amqp.Table{headerReturns: int32(3)}, want: 3},
+		{name: "int64", headers: amqp.Table{headerReturns: int64(4)}, want: 4},
+		{name: "unexpected type", headers: amqp.Table{headerReturns: "5"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := returnCount(tt.headers); got != tt.want {
+				t.Fatalf("returnCount() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package queue
+
+import (
+	"fmt"
+	"log"
+)
+
+// WorkerCounts returns the number of running consumers per queue
+func (rabbitMqService *RabbitMqService) WorkerCounts() map[string]int {
+	rabbitMqService.poolsMu.RLock()
+	defer rabbitMqService.poolsMu.RUnlock()
+
+	counts := make(map[string]int, len(rabbitMqService.pools))
+	for queueName, pool := range rabbitMqService.pools {
+		counts[queueName] = pool.size()
+	}
+	return counts
+}
+
+// ScaleWorkers sets the number of consumers of queueName, 0 pauses consuming the queue
+func (rabbitMqService *RabbitMqService) ScaleWorkers(queueName string, count int) error {
+	if count < 0 {
+		return fmt.Errorf("worker count must not be negative, got %d", count)
+	}
+	rabbitMqService.poolsMu.RLock()
+	pool, ok := rabbitMqService.pools[queueName]
+	rabbitMqService.poolsMu.RUnlock()
+	if !ok {
+		return fmt.Errorf("unknown queue %s", queueName)
+	}
+	pool.scale(count)
+	return nil
+}
+
+// ReloadWorkers reads the worker counts again, see config.LoadWorkerCounts, and scales every queue to them
+func (rabbitMqService *RabbitMqService) ReloadWorkers() error {
+	counts, err := rabbitMqService.config.LoadWorkerCounts()
+	if err != nil {
+		return fmt.Errorf("failed to reload worker counts: %w", err)
+	}
+	for queueName, count := range counts {
+		if err := rabbitMqService.ScaleWorkers(queueName, count); err != nil {
+			return err
+		}
+	}
+	log.Printf("worker counts reloaded: %v", rabbitMqService.WorkerCounts())
+	return nil
+}
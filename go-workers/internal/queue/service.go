@@ -5,42 +5,62 @@ import (
 	"errors"
 	"fmt"
 	"log"
-	"strings"
+	"os"
+	"sync"
 	"time"
 
 	"github.com/mahirjain10/go-workers/config"
+	"github.com/mahirjain10/go-workers/internal/admission"
 	"github.com/mahirjain10/go-workers/internal/aws"
+	"github.com/mahirjain10/go-workers/internal/idempotency"
+	"github.com/mahirjain10/go-workers/internal/message"
+	"github.com/mahirjain10/go-workers/internal/outbox"
 	queueErrors "github.com/mahirjain10/go-workers/internal/queue/errors"
 	"github.com/mahirjain10/go-workers/internal/queue/handlers"
 	"github.com/mahirjain10/go-workers/internal/queue/models"
+	"github.com/mahirjain10/go-workers/internal/retry"
 	"github.com/mahirjain10/go-workers/internal/types"
 	"github.com/mahirjain10/go-workers/internal/utils"
 	amqp "github.com/rabbitmq/amqp091-go"
 )
 
 type RabbitMqService struct {
-	s3Service          *aws.S3Service
-	config             *config.Config
-	rabbitMqConn       *amqp.Connection
-	statusQueueChannel *amqp.Channel
-	transformHandler   *handlers.TransformHandler
+	s3Service        *aws.S3Service
+	config           *config.Config
+	connections      *connectionManager
+	transformHandler *handlers.TransformHandler
+	idempotencyStore idempotency.Store
+	statusPublisher  *statusPublisher
+	// workers tracks consumer goroutines and cleanups the background cleanups, both are drained on shutdown
+	workers  sync.WaitGroup
+	cleanups sync.WaitGroup
+	// pools holds the consumer pool of every consumed queue, filled by Start
+	poolsMu sync.RWMutex
+	pools   map[string]*workerPool
+	// userLimiter keeps a single user's burst from starving everyone else
+	userLimiter *admission.UserLimiter
+	// cancellations tracks cancelled job ids and the in-flight jobs they abort
+	cancellations *cancellations
 }
 
-func NewRabbitMqService(s3Service *aws.S3Service, rabbitMqConn *amqp.Connection, config *config.Config) *RabbitMqService {
-	return &RabbitMqService{
+func NewRabbitMqService(s3Service *aws.S3Service, rabbitMqConn *amqp.Connection, config *config.Config, idempotencyStore idempotency.Store, statusOutbox *outbox.Outbox) *RabbitMqService {
+	rabbitMqService := &RabbitMqService{
 		s3Service:        s3Service,
-		rabbitMqConn:     rabbitMqConn,
 		config:           config,
-		transformHandler: handlers.NewTransformHandler(s3Service),
+		transformHandler: handlers.NewTransformHandler(s3Service, config),
+		idempotencyStore: idempotencyStore,
+		statusPublisher:  newStatusPublisher(statusOutbox),
+		pools:            make(map[string]*workerPool),
+		userLimiter:      admission.NewUserLimiter(config.UserLimits),
+		cancellations:    newCancellations(config.CancelTTL),
 	}
+	rabbitMqService.connections = newConnectionManager(config.RabbitMqURL, rabbitMqConn, rabbitMqService.setupConnection)
+	return rabbitMqService
 }
 
-func (rabbitMqService *RabbitMqService) declareExchange() error {
-	if rabbitMqService.statusQueueChannel == nil {
-		return fmt.Errorf("statusQueueChannel is nil, cannot declare exchange")
-	}
-	err := rabbitMqService.statusQueueChannel.ExchangeDeclare(
-		"image_processing",
+func (rabbitMqService *RabbitMqService) declareExchange(ch *amqp.Channel) error {
+	err := ch.ExchangeDeclare(
+		rabbitMqService.config.Routing.StatusExchange,
 		"direct",
 		true,
 		false,
@@ -55,8 +75,11 @@ func (rabbitMqService *RabbitMqService) declareExchange() error {
 }
 
 func (rabbitMqService *RabbitMqService) fireBackgroundCleanup(parentCtx context.Context, downloadPath, uploadPath, s3Key, cleanupMode string) {
+	rabbitMqService.cleanups.Add(1)
 	go func() {
-		ctx, cancel := context.WithTimeout(parentCtx, 90*time.Second)
+		defer rabbitMqService.cleanups.Done()
+		// Cleanups outlive the deadline or cancel of the job that fired them
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(parentCtx), 90*time.Second)
 		defer cancel()
 
 		switch cleanupMode {
@@ -75,6 +98,17 @@ func (rabbitMqService *RabbitMqService) fireBackgroundCleanup(parentCtx context.
 				log.Printf("[bg-cleanup] error while removing local processed file %v", err)
 			}
 			utils.DeleteS3Object(ctx, rabbitMqService.s3Service, s3Key)
+		case "remove_local_all":
+			if err := utils.RemoveLocalRaw(downloadPath, s3Key); err != nil && !errors.Is(err, os.ErrNotExist) {
+				log.Printf("[bg-cleanup] error while removing local raw file %v", err)
+			}
+			if err := utils.RemoveLocalProcessed(uploadPath, s3Key); err != nil && !errors.Is(err, os.ErrNotExist) {
+				log.Printf("[bg-cleanup] error while removing local processed file %v", err)
+			}
+		case "remove_local_raw":
+			if err := utils.RemoveLocalRaw(downloadPath, s3Key); err != nil {
+				log.Printf("[bg-cleanup] error while removing local raw file %v", err)
+			}
 		case "cleanup_all":
 			utils.CleanupAll(ctx, rabbitMqService.s3Service, downloadPath, uploadPath, s3Key)
 		default:
@@ -86,10 +120,16 @@ func (rabbitMqService *RabbitMqService) fireBackgroundCleanup(parentCtx context.
 func (rabbitMqService *RabbitMqService) PublishToChannelHelper(ctx context.Context, id string, userId string, status string, publicUrl string, errorMsg string) error {
 	// DONT NEED CONTEXT HERE
 	statusData := utils.InitStatusData(id, userId, status, publicUrl, errorMsg)
+	return rabbitMqService.PublishStatusData(ctx, statusData)
+}
+
+// PublishStatusData publishes an already built status payload, used when the event carries more than the basic fields
+func (rabbitMqService *RabbitMqService) PublishStatusData(ctx context.Context, statusData *types.StatusData) error {
+	status := statusData.Status
 	statusMessage := utils.InitStatusMessage(statusData)
 	log.Printf("[%s] printing status message: %+v", status, statusMessage)
 	if err := rabbitMqService.PublishToChannel(ctx, statusMessage); err != nil {
-		if utils.IsFatalError(err) {
+		if queueErrors.IsFatal(err) {
 			return fmt.Errorf("fatal: cannot publish initial processing status: %w", err)
 		}
 		log.Printf("Warning: failed to publish initial status: %v", err)
@@ -97,28 +137,21 @@ func (rabbitMqService *RabbitMqService) PublishToChannelHelper(ctx context.Conte
 	return nil
 }
 
-func (rabbitMqService *RabbitMqService) PublishToChannel(ctx context.Context, message interface{}) error {
-	if rabbitMqService.statusQueueChannel == nil {
-		return fmt.Errorf("statusQueueChannel is not initialized")
-	}
+// PublishToChannel encodes the status in STATUS_CONTENT_TYPE and hands it to the status publisher, once it
+// is in the outbox it is retried until the broker confirms it, so only serialization and outbox failures are returned
+func (rabbitMqService *RabbitMqService) PublishToChannel(ctx context.Context, statusMessage types.StatusMessage) error {
 	// EXTENDING BG CONTEXT HERE
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
-	serializedMessage, err := utils.SerializeJSON(message)
+	contentType := rabbitMqService.config.StatusContentType
+	serializedMessage, err := message.EncodeStatus(contentType, statusMessage)
 	if err != nil {
 		return fmt.Errorf("failed to serialize message: %w", err)
 	}
 
-	err = rabbitMqService.statusQueueChannel.PublishWithContext(ctx,
-		"image_processing",
-		"status",
-		true,
-		false,
-		amqp.Publishing{
-			ContentType: "application/json",
-			Body:        serializedMessage,
-		})
+	routing := rabbitMqService.config.Routing
+	err = rabbitMqService.statusPublisher.publish(ctx, statusMessage.Data.ID, routing.StatusExchange, routing.StatusRoutingKey, contentType, serializedMessage)
 	if err != nil {
 		return fmt.Errorf("failed to publish message: %w", err)
 	}
@@ -126,264 +159,487 @@ func (rabbitMqService *RabbitMqService) PublishToChannel(ctx context.Context, me
 	return nil
 }
 
-func (rabbitMqService *RabbitMqService) ProcessMessage(ctx context.Context, d amqp.Delivery) error {
-	status := types.PROCESSING
-	_, downloadPath, uploadPath := rabbitMqService.s3Service.GetDependencyData()
-	log.Printf("Received message: %s", d.Body)
-	var publicUrl, errorMsg = "", ""
-	var rabbitMqMessage *models.RabbitMqMessage
-	if err := utils.ParseJSON(d.Body, &rabbitMqMessage); err != nil {
-		return fmt.Errorf("failed to parse message: %w", err)
+// attachPlaceholders fills BlurHash and LQIP from the processed file, a failure here never fails the job
+func (rabbitMqService *RabbitMqService) attachPlaceholders(ctx context.Context, statusData *types.StatusData, processedKey string) {
+	blurHash, lqip, err := rabbitMqService.transformHandler.Placeholders(ctx, processedKey)
+	if err != nil {
+		log.Printf("[placeholder] error while generating placeholders: %v", err)
+		return
 	}
+	statusData.BlurHash = blurHash
+	statusData.Lqip = lqip
+}
 
-	log.Printf("Processing S3 event - Pattern: %s, Object: %+v", rabbitMqMessage.Pattern, rabbitMqMessage.Data)
-	if err := rabbitMqService.PublishToChannelHelper(ctx, rabbitMqMessage.Data.Id, rabbitMqMessage.Data.UserId, status, publicUrl, errorMsg); err != nil {
+// publishCompleted publishes the PROCESSED status of a job and records it, so a redelivery of the same job
+// only re-publishes this status
+func (rabbitMqService *RabbitMqService) publishCompleted(ctx context.Context, statusData *types.StatusData) error {
+	if err := rabbitMqService.PublishStatusData(ctx, statusData); err != nil {
 		return err
 	}
+	if err := rabbitMqService.idempotencyStore.Put(ctx, statusData.ID, statusData); err != nil {
+		log.Printf("[idempotency] failed to record completed job %s: %v", statusData.ID, err)
+	}
+	return nil
+}
 
-	if rabbitMqMessage.Data.CreatedAt != "" {
-		log.Printf("Message created at: %s, processing now - check for delays", rabbitMqMessage.Data.CreatedAt)
+// replayCompleted re-publishes the recorded status when the job already completed, it reports whether it did.
+// A job recorded as CANCELLED was cancelled while it was queued, see consumeStoredCancellations.
+func (rabbitMqService *RabbitMqService) replayCompleted(ctx context.Context, data types.ImageProcessing) (bool, error) {
+	previous, ok, err := rabbitMqService.idempotencyStore.Get(ctx, data.Id)
+	if err != nil {
+		// Failing open: processing twice is better than never processing
+		log.Printf("[idempotency] failed to look up job %s, processing it: %v", data.Id, err)
+		return false, nil
+	}
+	if !ok {
+		return false, nil
 	}
+	if previous.Status == types.CANCELLED {
+		log.Printf("job %s was cancelled while queued, dropping it", data.Id)
+		return true, rabbitMqService.publishCancelled(ctx, data)
+	}
+	log.Printf("[idempotency] job %s already completed, re-publishing its %s status", data.Id, previous.Status)
+	return true, rabbitMqService.PublishStatusData(ctx, previous)
+}
 
-	// Download from S3
-	var downloadErr error
-	for i := 0; i < 3; i++ {
-		downloadErr = rabbitMqService.s3Service.DownloadFromS3Object(ctx, rabbitMqMessage.Data.S3RawKey)
-		if downloadErr == nil {
-			break
-		}
-		log.Printf("%d try: error while downloading S3 object: %v", i+1, downloadErr)
-		if i < 2 {
-			time.Sleep(2 * time.Second)
+// classifyFailure classifies cause, falling back to the error of the failed step
+func classifyFailure(ctx context.Context, cause error, fallback *queueErrors.Error) *queueErrors.Error {
+	// A cancelled job fails on its aborted context, ProcessMessage publishes CANCELLED instead
+	if errors.Is(context.Cause(ctx), queueErrors.ErrCancelled) {
+		return queueErrors.Wrap(queueErrors.ErrCancelled, cause)
+	}
+	// Whatever step was running when the deadline passed, the job failed because it took too long
+	if errors.Is(context.Cause(ctx), queueErrors.ErrTimeout) {
+		fallback = queueErrors.ErrTimeout
+		if !errors.Is(cause, queueErrors.ErrTimeout) {
+			cause = queueErrors.Wrap(queueErrors.ErrTimeout, cause)
 		}
 	}
+	return queueErrors.Classify(cause, fallback)
+}
 
-	if downloadErr != nil {
-		log.Printf("Download failed after 3 attempts: %v", downloadErr)
-		errorMsg = queueErrors.ErrDownload
-		status := types.FAILED
-		if err := rabbitMqService.PublishToChannelHelper(ctx, rabbitMqMessage.Data.Id, rabbitMqMessage.Data.UserId, status, publicUrl, errorMsg); err != nil {
-			return err
-		}
+// jobFailed returns the error a failed job hands to handleFailure. Transient failures are retried while
+// tiers remain, the FAILED status and cleanupMode wait until the delivery is dead lettered.
+func (rabbitMqService *RabbitMqService) jobFailed(ctx context.Context, data types.ImageProcessing, cause error, fallback *queueErrors.Error, cleanupMode string) error {
+	jobErr := classifyFailure(ctx, cause, fallback)
+	// A job past its deadline or cancelled would only end the same way again
+	requeue := queueErrors.IsTransient(jobErr) && jobErr.Code != queueErrors.CodeTimeout && jobErr.Code != queueErrors.CodeCancelled
+	return models.ProcessingError{Err: jobErr, Requeue: requeue, Job: &data, Cleanup: cleanupMode}
+}
 
-		// background: just delete the S3 object 
-		rabbitMqService.fireBackgroundCleanup(ctx, downloadPath, uploadPath, rabbitMqMessage.Data.S3RawKey, "delete_s3")
-		return models.ProcessingError{Err: fmt.Errorf("download failed for key %s: %w", rabbitMqMessage.Data.S3RawKey, downloadErr), Requeue: false}
+// publishFailed publishes the FAILED status of a dead lettered job, carrying the code and message of err
+func (rabbitMqService *RabbitMqService) publishFailed(ctx context.Context, data types.ImageProcessing, err error) error {
+	var jobErr *queueErrors.Error
+	if !errors.As(err, &jobErr) {
+		jobErr = queueErrors.Classify(err, queueErrors.ErrTransform)
 	}
+	// The job context may be past its deadline, the status must still go out
+	ctx = context.WithoutCancel(ctx)
+	statusData := utils.InitStatusData(data.Id, data.UserId, types.FAILED, "", jobErr.Msg)
+	statusData.ErrorCode = string(jobErr.Code)
+	return rabbitMqService.PublishStatusData(ctx, statusData)
+}
 
-	// Transform image
-	if err := rabbitMqService.transformHandler.TransformImage(rabbitMqMessage.Data); err != nil {
-		log.Printf("Transform failed: %v", err)
-		errorMsg = queueErrors.ErrTransform
-		status := types.FAILED
-		if err := rabbitMqService.PublishToChannelHelper(ctx, rabbitMqMessage.Data.Id, rabbitMqMessage.Data.UserId, status, publicUrl, errorMsg); err != nil {
-			return err
+// processHistogram finishes a HISTOGRAM job, uploading the rendered PNG when one was requested
+func (rabbitMqService *RabbitMqService) processHistogram(ctx context.Context, data types.ImageProcessing, downloadPath string, uploadPath string) error {
+	histogram, renderedKey, err := rabbitMqService.transformHandler.HistogramImage(ctx, data)
+	if err != nil {
+		log.Printf("Histogram failed: %v", err)
+		return rabbitMqService.jobFailed(ctx, data, err, queueErrors.ErrTransform, "remove_local_raw")
+	}
+
+	publicUrl := ""
+	if renderedKey != "" {
+		var uploadErr error
+		publicUrl, uploadErr = rabbitMqService.s3Service.UploadtoS3Object(ctx, renderedKey)
+		if err := utils.RemoveLocalFile(uploadPath, renderedKey); err != nil {
+			log.Printf("error while removing rendered histogram %v", err)
+		}
+		if uploadErr != nil {
+			return rabbitMqService.jobFailed(ctx, data, uploadErr, queueErrors.ErrUpload, "remove_local_raw")
 		}
+	}
 
-		// background: remove local raw and delete s3
-		rabbitMqService.fireBackgroundCleanup(ctx, downloadPath, uploadPath, rabbitMqMessage.Data.S3RawKey, "remove_local_and_delete_s3")
-		return models.ProcessingError{Err: fmt.Errorf("transform failed for key %s: %w", rabbitMqMessage.Data.S3RawKey, err), Requeue: false}
+	statusData := utils.InitStatusData(data.Id, data.UserId, types.PROCCESSED, publicUrl, "")
+	statusData.Histogram = histogram
+	if err := rabbitMqService.publishCompleted(ctx, statusData); err != nil {
+		return err
 	}
 
-	// Handle upload
-	splitString := strings.Split(rabbitMqMessage.Data.S3RawKey, "/")
-	if len(splitString) < 2 {
-		errorMsg = queueErrors.ErrUpload
-		status = types.FAILED
-		_ = rabbitMqService.PublishToChannelHelper(ctx, rabbitMqMessage.Data.Id, rabbitMqMessage.Data.UserId, status, publicUrl, errorMsg)
-		return models.ProcessingError{Err: fmt.Errorf("unexpected S3RawKey format: %s", rabbitMqMessage.Data.S3RawKey), Requeue: false}
+	rabbitMqService.fireBackgroundCleanup(ctx, downloadPath, uploadPath, data.S3RawKey, "remove_local_raw")
+	return nil
+}
+
+// processInspection finishes an INSPECT job, the raw object is kept in S3 since there is no processed asset replacing it
+func (rabbitMqService *RabbitMqService) processInspection(ctx context.Context, data types.ImageProcessing, downloadPath string, uploadPath string) error {
+	inspection, err := rabbitMqService.transformHandler.InspectImage(ctx, data)
+	if err != nil {
+		log.Printf("Inspection failed: %v", err)
+		return rabbitMqService.jobFailed(ctx, data, err, queueErrors.ErrTransform, "remove_local_raw")
 	}
 
-	finalKey := splitString[1]
-	if rabbitMqMessage.Data.TransformationType == "CONVERT" {
-		var convert types.Convert
-		if err := utils.ParseJSON([]byte(rabbitMqMessage.Data.TransformationParameters), &convert); err != nil {
-			return fmt.Errorf("failed to parse message: %w", err)
-		}
-	
-		dotIndex := strings.LastIndex(finalKey, ".")
-		if dotIndex == -1 {
-			return fmt.Errorf("unexpected S3RawKey format: %s", rabbitMqMessage.Data.S3RawKey)
-		}
-		base := finalKey[:dotIndex]
-		finalKey = fmt.Sprintf("%s.%s", base, convert.Format)
+	log.Printf("Inspection for %s passed: %t, reasons: %v", data.Id, inspection.Passed, inspection.Reasons)
+	statusData := utils.InitStatusData(data.Id, data.UserId, types.PROCCESSED, "", "")
+	statusData.Inspection = inspection
+	if err := rabbitMqService.publishCompleted(ctx, statusData); err != nil {
+		return err
 	}
 
-	formattedKey := fmt.Sprintf("processed/%s", finalKey)
+	rabbitMqService.fireBackgroundCleanup(ctx, downloadPath, uploadPath, data.S3RawKey, "remove_local_raw")
+	return nil
+}
 
-	// Upload to S3
-	var uploadErr error
-	for i := 0; i < 3; i++ {
-		publicUrl, uploadErr = rabbitMqService.s3Service.UploadtoS3Object(ctx, formattedKey)
-		log.Printf("i:%d Public URL: %s and err: %v", i, publicUrl, uploadErr)
-		if uploadErr == nil {
-			break
-		}
-		if i < 2 {
-			time.Sleep(2 * time.Second)
-		}
+// ProcessMessage runs one decoded job: replays, cancels and expiry first, then the job itself within
+// its deadline
+func (rabbitMqService *RabbitMqService) ProcessMessage(ctx context.Context, queueName string, data types.ImageProcessing) error {
+	log.Printf("Processing S3 event - Object: %+v", data)
+	// A redelivery after a crash must not download, transform and upload again
+	if replayed, err := rabbitMqService.replayCompleted(ctx, data); replayed || err != nil {
+		return err
+	}
+	if rabbitMqService.cancellations.isCancelled(data.Id) {
+		log.Printf("job %s was cancelled while queued, dropping it", data.Id)
+		return rabbitMqService.publishCancelled(ctx, data)
 	}
 
-	if uploadErr != nil {
-		fmt.Printf("error in upload: %v", uploadErr)
-		errorMsg = queueErrors.ErrUpload
-		status = types.FAILED
-		if err := rabbitMqService.PublishToChannelHelper(ctx, rabbitMqMessage.Data.Id, rabbitMqMessage.Data.UserId, status, publicUrl, errorMsg); err != nil {
-			return err
-		}
+	if expired, err := rabbitMqService.checkJobAge(ctx, queueName, data); expired || err != nil {
+		return err
+	}
 
-		rabbitMqService.fireBackgroundCleanup(ctx, downloadPath, uploadPath, rabbitMqMessage.Data.S3RawKey, "remove_local_all_and_delete_s3")
-		return models.ProcessingError{Err: fmt.Errorf("upload failed for key %s: %w", formattedKey, uploadErr), Requeue: false}
+	// A cancel for this job aborts jobCtx, see cancellation.go
+	jobCtx, done := rabbitMqService.cancellations.track(ctx, data.Id)
+	defer done()
+	timeout := rabbitMqService.config.TimeoutFor(data.TransformationType)
+	jobCtx, cancelDeadline := context.WithTimeoutCause(jobCtx, timeout, queueErrors.ErrTimeout)
+	defer cancelDeadline()
+	err := rabbitMqService.processJob(jobCtx, data)
+	// A cancel that lands after the job finished must not overwrite its PROCESSED status
+	if err != nil && errors.Is(context.Cause(jobCtx), queueErrors.ErrCancelled) {
+		log.Printf("job %s was cancelled while processing: %v", data.Id, err)
+		_, downloadPath, uploadPath := rabbitMqService.s3Service.GetDependencyData()
+		rabbitMqService.fireBackgroundCleanup(ctx, downloadPath, uploadPath, data.S3RawKey, "remove_local_all")
+		return rabbitMqService.publishCancelled(ctx, data)
+	}
+	return err
+}
+
+// rejectMessage dead letters a message that does not match its schema. When it decoded far enough to
+// know the job and its owner a FAILED status tells them why, redelivering it would fail the same way.
+func (rabbitMqService *RabbitMqService) rejectMessage(ctx context.Context, data types.ImageProcessing, err error) error {
+	log.Printf("rejecting job message: %v", err)
+	if data.Id == "" || data.UserId == "" {
+		return models.ProcessingError{Err: fmt.Errorf("failed to parse message: %w", err), Requeue: false}
+	}
+	return rabbitMqService.jobFailed(ctx, data, err, queueErrors.ErrInvalidMessage, "")
+}
+
+// processJob downloads, transforms and uploads one job, publishing its status along the way
+func (rabbitMqService *RabbitMqService) processJob(ctx context.Context, data types.ImageProcessing) error {
+	status := types.PROCESSING
+	_, downloadPath, uploadPath := rabbitMqService.s3Service.GetDependencyData()
+	var publicUrl, errorMsg = "", ""
+	if err := rabbitMqService.PublishToChannelHelper(ctx, data.Id, data.UserId, status, publicUrl, errorMsg); err != nil {
+		return err
+	}
+
+	handler, ok := handlerFor(data.TransformationType)
+	if !ok {
+		typeErr := fmt.Errorf("unsupported transformation type: %s", data.TransformationType)
+		return rabbitMqService.jobFailed(ctx, data, typeErr, queueErrors.ErrInvalidParameters, "")
+	}
+
+	// Download from S3, retried according to the queue's retry policy
+	downloadErr := rabbitMqService.s3Service.DownloadFromS3Object(ctx, data.S3RawKey)
+	if downloadErr != nil {
+		log.Printf("Download failed: %v", downloadErr)
+		return rabbitMqService.jobFailed(ctx, data, downloadErr, queueErrors.ErrDownload, "delete_s3")
+	}
+
+	return handler(rabbitMqService, ctx, data, downloadPath, uploadPath)
+}
+
+// processTransform finishes the jobs of every registered transformation.Transformer
+func (rabbitMqService *RabbitMqService) processTransform(ctx context.Context, data types.ImageProcessing, downloadPath string, uploadPath string) error {
+	var errorMsg = ""
+
+	// Transform image, the handler also decides the processed key
+	formattedKey, err := rabbitMqService.transformHandler.TransformImage(ctx, data)
+	if err != nil {
+		log.Printf("Transform failed: %v", err)
+		return rabbitMqService.jobFailed(ctx, data, err, queueErrors.ErrTransform, "remove_local_and_delete_s3")
+	}
+
+	// Upload to S3
+	publicUrl, uploadErr := rabbitMqService.s3Service.UploadtoS3Object(ctx, formattedKey)
+	if uploadErr != nil {
+		log.Printf("Upload failed: %v", uploadErr)
+		return rabbitMqService.jobFailed(ctx, data, uploadErr, queueErrors.ErrUpload, "remove_local_all_and_delete_s3")
 	}
 
 	// Mark as processed
-	status = types.PROCCESSED
-	if err := rabbitMqService.PublishToChannelHelper(ctx, rabbitMqMessage.Data.Id, rabbitMqMessage.Data.UserId, status, publicUrl, errorMsg); err != nil {
+	status := types.PROCCESSED
+	statusData := utils.InitStatusData(data.Id, data.UserId, status, publicUrl, errorMsg)
+	if rabbitMqService.config.EnablePlaceholders {
+		rabbitMqService.attachPlaceholders(ctx, statusData, formattedKey)
+	}
+	if err := rabbitMqService.publishCompleted(ctx, statusData); err != nil {
 		return err
 	}
 
-	rabbitMqService.fireBackgroundCleanup(ctx, downloadPath, uploadPath, rabbitMqMessage.Data.S3RawKey, "cleanup_all")
+	rabbitMqService.fireBackgroundCleanup(ctx, downloadPath, uploadPath, data.S3RawKey, "cleanup_all")
 	return nil
 }
 
-func (rabbitMqService *RabbitMqService) Start(conn *amqp.Connection, ctx context.Context) error {
-	for _, queueName := range rabbitMqService.config.RabbitMqQueues {
-		q := queueName
+// setupConnection runs on every new connection: it declares the topology, which is idempotent, and opens
+// the status publishing channel
+func (rabbitMqService *RabbitMqService) setupConnection(conn *amqp.Connection) error {
+	ch, err := utils.NewChannel(conn)
+	if err != nil {
+		return err
+	}
+	defer ch.Close()
 
-		ch, err := utils.NewChannel(conn)
-		if err != nil {
-			conn.Close()
-			log.Fatal("failed to open RabbitMQ channel :", err)
+	if err := utils.DeclareControlExchange(ch); err != nil {
+		return err
+	}
+	if err := utils.DeclareCancelQueue(ch, rabbitMqService.config.CancelTTL); err != nil {
+		return err
+	}
+	routing := rabbitMqService.config.Routing
+	// The status queue is declared by the API with plain arguments too, only work queues get priorities
+	if _, err := utils.NewQueue(ch, routing.StatusQueue, 0); err != nil {
+		return fmt.Errorf("failed to declare %s : %w", routing.StatusQueue, err)
+	}
+	if err := rabbitMqService.declareExchange(ch); err != nil {
+		return err
+	}
+	if err := ch.QueueBind(
+		routing.StatusQueue,
+		routing.StatusRoutingKey,
+		routing.StatusExchange,
+		false,
+		nil,
+	); err != nil {
+		return fmt.Errorf("failed to bind status queue: %w", err)
+	}
+	log.Printf("[%s] declared", routing.StatusQueue)
+
+	if routing.Mode == config.RoutingModeTopic {
+		if err := utils.DeclareJobsExchange(ch, routing.JobsExchange); err != nil {
+			return err
 		}
+	}
 
-		_, err = utils.NewQueue(ch, queueName)
-		if err != nil {
-			ch.Close()
-			conn.Close()
-			log.Fatalf("failed to declare %s : %v", queueName, err)
+	for shard, queueName := range rabbitMqService.config.WorkQueues() {
+		if _, err := utils.NewQueue(ch, queueName, rabbitMqService.config.QueueMaxPriority); err != nil {
+			return fmt.Errorf("failed to declare %s : %w", queueName, err)
 		}
-		log.Printf("[%s] declared", queueName)
-		if q == "status_queue" {
-			rabbitMqService.statusQueueChannel = ch
-			if err = rabbitMqService.declareExchange(); err != nil {
-				log.Fatalf("Failed to declare exchange: %v", err)
+		if routing.Mode == config.RoutingModeTopic {
+			if err := ch.QueueBind(queueName, routing.ShardBindingKey(shard), routing.JobsExchange, false, nil); err != nil {
+				return fmt.Errorf("failed to bind %s : %w", queueName, err)
 			}
-			err = ch.QueueBind(
-				"status_queue",
-				"status",
-				"image_processing",
-				false,
-				nil,
-			)
-			if err != nil {
-				log.Fatalf("Failed to bind status queue: %v", err)
+		}
+		log.Printf("[%s] declared", queueName)
+
+		if err := utils.DeclareDeadLetterQueue(ch, queueName); err != nil {
+			return fmt.Errorf("failed to declare dead letter queue for %s : %w", queueName, err)
+		}
+		if err := utils.DeclareRetryQueues(ch, queueName, rabbitMqService.config.RetryTiers); err != nil {
+			return fmt.Errorf("failed to declare retry queues for %s : %w", queueName, err)
+		}
+		if rabbitMqService.config.UserLimits.Enabled() {
+			if err := utils.DeclareDeferQueue(ch, queueName, rabbitMqService.config.UserDeferDelay); err != nil {
+				return fmt.Errorf("failed to declare deferred queue for %s : %w", queueName, err)
 			}
-			log.Println("started queue and channel for status queue, skipping the consuming")
-			continue
 		}
+		log.Printf("[%s] dead letter and retry queues declared", queueName)
+	}
+
+	return rabbitMqService.openStatusChannel(conn)
+}
 
+// openStatusChannel opens the channel status messages are published on and keeps it open for as long as
+// conn lives, a lost connection is handled by the connection manager instead
+func (rabbitMqService *RabbitMqService) openStatusChannel(conn *amqp.Connection) error {
+	ch, err := utils.NewChannel(conn)
+	if err != nil {
+		return err
+	}
+	if err := rabbitMqService.statusPublisher.attach(ch); err != nil {
 		ch.Close()
+		return fmt.Errorf("failed to set up status publisher: %w", err)
+	}
+	log.Println("status channel opened")
 
-		count, ok := config.Worker[queueName]
-		if !ok {
-			log.Printf("could not get worker count for [%s]", queueName)
-			count = 1
+	go func() {
+		amqpErr, ok := <-ch.NotifyClose(make(chan *amqp.Error, 1))
+		if !ok || conn.IsClosed() {
+			return
 		}
-		for i := range count {
-			log.Printf("[%s] started :  worker no %d", queueName, i+1)
-			go func(queueName string) {
-				var consumerCh *amqp.Channel
-				defer func() {
-					if consumerCh != nil {
-						consumerCh.Close()
-					}
-				}()
-
-				for {
-					select {
-					case <-ctx.Done():
-						log.Printf("[%s] Shutting down...", queueName)
-						return
-					default:
-					}
-
-					if consumerCh == nil || consumerCh.IsClosed() {
-						if consumerCh != nil {
-							consumerCh.Close()
-						}
-
-						conn, err := utils.NewRabbitMQClient(rabbitMqService.config.RabbitMqURL)
-						if err != nil {
-							log.Printf("failed to connect to RabbitMQ: %v", err)
-						}
-						rabbitMqService.rabbitMqConn = conn
-						newCh, err := utils.NewChannel(conn)
-						if err != nil {
-							log.Printf("[%s] Failed to create channel: %v", queueName, err)
-							time.Sleep(5 * time.Second)
-							continue
-						}
-						consumerCh = newCh
-						log.Printf("[%s] Channel created", queueName)
-					}
-
-					msgs, err := utils.NewQueueConsumer(consumerCh, queueName)
-					if err != nil {
-						log.Printf("[%s] Failed to start consumer: %v", queueName, err)
-						consumerCh.Close()
-						consumerCh = nil
-						time.Sleep(5 * time.Second)
-						continue
-					}
-
-					log.Printf("[%s] Worker started, waiting for messages...", queueName)
-
-					channelClosed := false
-					for !channelClosed {
-						select {
-						case <-ctx.Done():
-							log.Printf("[%s] Shutting down...", queueName)
-							return
-						case d, ok := <-msgs:
-							if !ok {
-								log.Printf("[%s] Channel closed, will recreate", queueName)
-								consumerCh = nil
-								channelClosed = true
-								time.Sleep(2 * time.Second)
-								break
-							}
-
-							if err := rabbitMqService.ProcessMessage(ctx, d); err != nil {
-								log.Printf("[%s] Error processing message: %v", queueName, err)
-
-								var procErr models.ProcessingError
-								if errors.As(err, &procErr) {
-									if procErr.Requeue {
-										d.Nack(false, true)
-									} else {
-										d.Nack(false, false)
-									}
-									continue
-								}
-
-								if utils.IsTransientError(err) {
-									d.Nack(false, true)
-								} else {
-									d.Nack(false, false)
-								}
-								continue
-							}
-
-							d.Ack(false)
-						}
-					}
-				}
-			}(q)
+		log.Printf("status channel closed by broker: %v, reopening", amqpErr)
+		for attempt := 1; !conn.IsClosed(); attempt++ {
+			if err := rabbitMqService.openStatusChannel(conn); err == nil {
+				return
+			}
+			time.Sleep(reconnectBackoff.Backoff(attempt))
 		}
+	}()
+	return nil
+}
 
+// Start declares the topology and runs the consumers until ctx is cancelled, then stops consuming and
+// drains in-flight jobs before returning
+func (rabbitMqService *RabbitMqService) Start(ctx context.Context) error {
+	counts, err := rabbitMqService.config.LoadWorkerCounts()
+	if err != nil {
+		return err
+	}
+
+	if err := rabbitMqService.connections.start(); err != nil {
+		rabbitMqService.connections.close()
+		return fmt.Errorf("failed to set up RabbitMQ topology: %w", err)
+	}
+
+	// Jobs run on workCtx so a shutdown signal does not abort them mid-upload, drain cancels it once the
+	// shutdown timeout is over
+	workCtx, cancelWork := context.WithCancel(context.WithoutCancel(ctx))
+	go rabbitMqService.statusPublisher.relay(ctx, rabbitMqService.config.OutboxRetryInterval)
+	go rabbitMqService.consumeCancellations(ctx)
+	go rabbitMqService.consumeStoredCancellations(ctx)
+	go rabbitMqService.cancellations.pruneEvery(ctx, cancelPruneInterval)
+
+	for _, queueName := range rabbitMqService.config.WorkQueues() {
+		var pool *workerPool
+		pool = newWorkerPool(ctx, queueName, func(stop context.Context, consumerTag string) {
+			rabbitMqService.workers.Add(1)
+			go rabbitMqService.consume(stop, workCtx, pool, consumerTag)
+		})
+		rabbitMqService.poolsMu.Lock()
+		rabbitMqService.pools[queueName] = pool
+		rabbitMqService.poolsMu.Unlock()
+		count := counts[queueName]
+		if rabbitMqService.config.Autoscale.Enabled {
+			bounds := rabbitMqService.config.Autoscale.Bounds[queueName]
+			count = min(max(count, bounds.Min), bounds.Max)
+		}
+		pool.scale(count)
+	}
+	if rabbitMqService.config.Autoscale.Enabled {
+		go rabbitMqService.autoscale(ctx)
 	}
 
 	<-ctx.Done()
 	log.Println("Shutting down all consumers gracefully...")
+	rabbitMqService.drain(cancelWork)
 	return nil
 }
+
+// consume runs one consumer of the pool's queue until stop is done, jobs themselves run on workCtx so
+// stopping a consumer lets its in-flight job finish
+func (rabbitMqService *RabbitMqService) consume(stop context.Context, workCtx context.Context, pool *workerPool, consumerTag string) {
+	defer rabbitMqService.workers.Done()
+	queueName := pool.queueName
+	// S3 operations of this queue's jobs retry with the queue's own policy
+	retryPolicy := rabbitMqService.config.RetryPolicyFor(queueName)
+	prefetch := rabbitMqService.config.PrefetchFor(queueName)
+	log.Printf("[%s] started : %s, prefetch %d", queueName, consumerTag, prefetch)
+
+	var consumerCh *amqp.Channel
+	defer func() {
+		if consumerCh != nil {
+			consumerCh.Close()
+		}
+	}()
+
+	for {
+		select {
+		case <-stop.Done():
+			log.Printf("[%s] %s stopped", queueName, consumerTag)
+			return
+		default:
+		}
+
+		if consumerCh == nil || consumerCh.IsClosed() {
+			if consumerCh != nil {
+				consumerCh.Close()
+			}
+
+			// Blocks while the connection manager reconnects
+			newCh, err := rabbitMqService.connections.channel(stop)
+			if err != nil {
+				log.Printf("[%s] %s stopped", queueName, consumerTag)
+				return
+			}
+			consumerCh = newCh
+			log.Printf("[%s] Channel created", queueName)
+		}
+
+		msgs, err := utils.NewQueueConsumer(consumerCh, queueName, consumerTag, prefetch)
+		if err != nil {
+			log.Printf("[%s] Failed to start consumer: %v", queueName, err)
+			consumerCh.Close()
+			consumerCh = nil
+			time.Sleep(5 * time.Second)
+			continue
+		}
+
+		log.Printf("[%s] Worker started, waiting for messages...", queueName)
+
+		channelClosed := false
+		for !channelClosed {
+			select {
+			case <-stop.Done():
+				// basic.cancel stops new deliveries, prefetched unacked ones are requeued when the channel closes
+				log.Printf("[%s] Stopping, cancelling consumer %s", queueName, consumerTag)
+				if err := consumerCh.Cancel(consumerTag, false); err != nil {
+					log.Printf("[%s] error while cancelling consumer: %v", queueName, err)
+				}
+				return
+			case d, ok := <-msgs:
+				if !ok {
+					log.Printf("[%s] Channel closed, will recreate", queueName)
+					consumerCh = nil
+					channelClosed = true
+					break
+				}
+
+				finished := pool.working()
+				rabbitMqService.handleDelivery(stop, workCtx, consumerCh, queueName, d, retryPolicy)
+				finished()
+			}
+		}
+	}
+}
+
+// handleDelivery runs one delivery through admission and processing and settles it, unless stop is done
+// while it waits, the delivery is then requeued with the channel
+func (rabbitMqService *RabbitMqService) handleDelivery(stop context.Context, workCtx context.Context, ch *amqp.Channel, queueName string, d amqp.Delivery, retryPolicy retry.Policy) {
+	// The delivery stays unacked while paused, a stop requeues it with the channel
+	if !rabbitMqService.waitForAbandoned(stop, queueName) {
+		return
+	}
+
+	// Decoded once here, admission and processing all work on this job
+	log.Printf("Received message: %s", d.Body)
+	job, err := message.Decode(d.ContentType, d.Body)
+	if err != nil {
+		err = rabbitMqService.rejectMessage(workCtx, job, err)
+	} else {
+		if rabbitMqService.promotePriority(workCtx, ch, queueName, d, job) {
+			return
+		}
+		release, admitted := rabbitMqService.admitUser(workCtx, ch, queueName, d, job)
+		if !admitted {
+			return
+		}
+		err = rabbitMqService.ProcessMessage(retry.WithPolicy(workCtx, retryPolicy), queueName, job)
+		release()
+	}
+	if err != nil {
+		log.Printf("[%s] Error processing message: %v", queueName, err)
+		rabbitMqService.handleFailure(workCtx, ch, queueName, d, err)
+		return
+	}
+
+	d.Ack(false)
+}
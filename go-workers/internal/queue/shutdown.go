@@ -0,0 +1,53 @@
+package queue
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// waitTimeout waits for wg until the deadline, it reports whether wg finished in time
+func waitTimeout(wg *sync.WaitGroup, deadline time.Time) bool {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	timer := time.NewTimer(time.Until(deadline))
+	defer timer.Stop()
+	select {
+	case <-done:
+		return true
+	case <-timer.C:
+		return false
+	}
+}
+
+// drain runs once the shutdown signal arrived and the consumers were told to stop: it lets in-flight jobs
+// and background cleanups finish within the shutdown timeout, aborts whatever is left through cancelWork
+// and finally closes the connection.
+func (rabbitMqService *RabbitMqService) drain(cancelWork context.CancelFunc) {
+	deadline := time.Now().Add(rabbitMqService.config.ShutdownTimeout)
+	defer cancelWork()
+
+	log.Printf("[shutdown] waiting up to %s for in-flight jobs", rabbitMqService.config.ShutdownTimeout)
+	if !waitTimeout(&rabbitMqService.workers, deadline) {
+		log.Println("[shutdown] in-flight jobs did not finish in time, cancelling them")
+		cancelWork()
+		// Cancelled jobs still publish their status and requeue, give them a moment to do so
+		waitTimeout(&rabbitMqService.workers, time.Now().Add(5*time.Second))
+	}
+
+	log.Println("[shutdown] waiting for background cleanups")
+	if !waitTimeout(&rabbitMqService.cleanups, deadline) {
+		log.Println("[shutdown] background cleanups did not finish in time")
+	}
+
+	// Closing the connection closes the status and consumer channels with it
+	if err := rabbitMqService.connections.close(); err != nil {
+		log.Printf("[shutdown] error while closing connection: %v", err)
+	}
+	log.Println("[shutdown] done")
+}
@@ -0,0 +1,41 @@
+package queue
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/mahirjain10/go-workers/internal/metrics"
+	queueErrors "github.com/mahirjain10/go-workers/internal/queue/errors"
+	"github.com/mahirjain10/go-workers/internal/types"
+	"github.com/mahirjain10/go-workers/internal/utils"
+)
+
+// checkJobAge records how long the job waited since CreatedAt and rejects it as EXPIRED once it is older
+// than MaxJobAge. Jobs without a parseable CreatedAt are processed as before.
+func (rabbitMqService *RabbitMqService) checkJobAge(ctx context.Context, queueName string, data types.ImageProcessing) (bool, error) {
+	if data.CreatedAt == "" {
+		return false, nil
+	}
+	// Prisma serialises dates as RFC 3339 with milliseconds, e.g. 2025-01-02T15:04:05.000Z
+	createdAt, err := time.Parse(time.RFC3339Nano, data.CreatedAt)
+	if err != nil {
+		log.Printf("job %s has an invalid createdAt %q, skipping the age check: %v", data.Id, data.CreatedAt, err)
+		return false, nil
+	}
+
+	age := time.Since(createdAt)
+	metrics.ObserveQueueLatency(queueName, age)
+	log.Printf("job %s created at %s, waited %s", data.Id, data.CreatedAt, age.Round(time.Millisecond))
+
+	maxAge := rabbitMqService.config.MaxJobAge
+	if maxAge <= 0 || age <= maxAge {
+		return false, nil
+	}
+
+	log.Printf("job %s is %s old, over the maximum of %s, rejecting it", data.Id, age.Round(time.Second), maxAge)
+	metrics.IncExpired(queueName)
+	statusData := utils.InitStatusData(data.Id, data.UserId, types.EXPIRED, "", queueErrors.ErrExpired.Msg)
+	statusData.ErrorCode = string(queueErrors.CodeExpired)
+	return true, rabbitMqService.PublishStatusData(ctx, statusData)
+}
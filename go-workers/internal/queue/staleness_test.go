@@ -0,0 +1,65 @@
+package queue
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mahirjain10/go-workers/config"
+	"github.com/mahirjain10/go-workers/internal/message"
+	"github.com/mahirjain10/go-workers/internal/outbox"
+	"github.com/mahirjain10/go-workers/internal/types"
+)
+
+func TestCheckJobAge(t *testing.T) {
+	created := func(age time.Duration) string {
+		return time.Now().Add(-age).UTC().Format("2006-01-02T15:04:05.000Z07:00")
+	}
+	tests := []struct {
+		name      string
+		maxJobAge time.Duration
+		createdAt string
+		expired   bool
+	}{
+		{name: "no createdAt", maxJobAge: time.Hour},
+		{name: "invalid createdAt", maxJobAge: time.Hour, createdAt: "yesterday"},
+		{name: "fresh", maxJobAge: time.Hour, createdAt: created(time.Minute)},
+		{name: "check disabled", createdAt: created(48 * time.Hour)},
+		{name: "too old", maxJobAge: time.Hour, createdAt: created(2 * time.Hour), expired: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o, err := outbox.Open(filepath.Join(t.TempDir(), "outbox.db"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer o.Close()
+			// No channel is attached, so a published status stays in the outbox
+			rabbitMqService := &RabbitMqService{
+				config:          &config.Config{MaxJobAge: tt.maxJobAge, StatusContentType: message.ContentTypeJSON},
+				statusPublisher: newStatusPublisher(o),
+			}
+			data := types.ImageProcessing{Id: "job", UserId: "user", CreatedAt: tt.createdAt}
+
+			expired, err := rabbitMqService.checkJobAge(context.Background(), "convert_queue", data)
+			if err != nil || expired != tt.expired {
+				t.Fatalf("checkJobAge() = %t, %v, want %t, nil", expired, err, tt.expired)
+			}
+			pending, err := o.Pending()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !tt.expired {
+				if len(pending) != 0 {
+					t.Fatalf("%d statuses published for a job that is not expired", len(pending))
+				}
+				return
+			}
+			if len(pending) != 1 || !strings.Contains(string(pending[0].Body), types.EXPIRED) {
+				t.Fatalf("Pending() = %v, want one EXPIRED status", pending)
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"net"
+	"syscall"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsretry "github.com/aws/aws-sdk-go-v2/aws/retry"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+)
+
+// awsRetryables is the classification the AWS SDK itself uses: throttling and timeout codes,
+// connection errors and errors explicitly marked retryable
+var awsRetryables = awsretry.IsErrorRetryables(awsretry.DefaultRetryables)
+
+// nonRetryableCodes are API error codes retrying can never fix
+var nonRetryableCodes = map[string]bool{
+	"AccessDenied":          true,
+	"InvalidAccessKeyId":    true,
+	"SignatureDoesNotMatch": true,
+	"ExpiredToken":          true,
+	"InvalidBucketName":     true,
+	"NoSuchBucket":          true,
+	"NoSuchKey":             true,
+	"NotFound":              true,
+	"EntityTooLarge":        true,
+}
+
+// IsRetryable classifies an error from the typed AWS SDK and network errors it wraps, anything it does
+// not recognise is treated as permanent
+func IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	var noSuchKey *s3types.NoSuchKey
+	var noSuchBucket *s3types.NoSuchBucket
+	if errors.As(err, &noSuchKey) || errors.As(err, &noSuchBucket) {
+		return false
+	}
+
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) && nonRetryableCodes[apiErr.ErrorCode()] {
+		return false
+	}
+
+	// An attempt hitting its own deadline is worth another try, the caller checks the parent context
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+
+	var responseErr *awshttp.ResponseError
+	if errors.As(err, &responseErr) {
+		status := responseErr.HTTPStatusCode()
+		if status == 429 || status >= 500 {
+			return true
+		}
+	}
+
+	switch awsRetryables.IsErrorRetryable(err) {
+	case aws.TrueTernary:
+		return true
+	case aws.FalseTernary:
+		return false
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	return errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED)
+}
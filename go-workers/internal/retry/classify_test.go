@@ -0,0 +1,63 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"syscall"
+	"testing"
+
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+)
+
+// responseError is what the SDK returns for an HTTP error status
+func responseError(status int) error {
+	return &awshttp.ResponseError{
+		ResponseError: &smithyhttp.ResponseError{
+			Response: &smithyhttp.Response{Response: &http.Response{StatusCode: status}},
+			Err:      fmt.Errorf("status %d", status),
+		},
+	}
+}
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "nil", err: nil, want: false},
+		{name: "unknown error", err: errors.New("boom"), want: false},
+		{name: "cancelled", err: context.Canceled, want: false},
+		{name: "attempt deadline", err: fmt.Errorf("get object: %w", context.DeadlineExceeded), want: true},
+		{name: "no such key", err: &s3types.NoSuchKey{}, want: false},
+		{name: "no such bucket", err: fmt.Errorf("wrapped: %w", &s3types.NoSuchBucket{}), want: false},
+		{name: "access denied", err: &smithy.GenericAPIError{Code: "AccessDenied"}, want: false},
+		{name: "not found code", err: &smithy.GenericAPIError{Code: "NotFound"}, want: false},
+		{name: "throttled", err: &smithy.GenericAPIError{Code: "SlowDown"}, want: true},
+		{name: "http 429", err: responseError(429), want: true},
+		{name: "http 503", err: responseError(503), want: true},
+		{name: "http 400", err: responseError(400), want: false},
+		{name: "network timeout", err: &net.OpError{Op: "read", Err: timeoutError{}}, want: true},
+		{name: "connection reset", err: fmt.Errorf("read: %w", syscall.ECONNRESET), want: true},
+		{name: "connection refused", err: syscall.ECONNREFUSED, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsRetryable(tt.err); got != tt.want {
+				t.Fatalf("IsRetryable(%v) = %t, want %t", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
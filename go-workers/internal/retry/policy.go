@@ -0,0 +1,95 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"math/rand/v2"
+	"time"
+)
+
+// Policy describes how an operation is retried: up to MaxAttempts tries with an exponential backoff
+// starting at BaseDelay, capped at MaxDelay, randomised by +/- Jitter (a fraction of the delay).
+type Policy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+	Multiplier  float64
+	Jitter      float64
+}
+
+// DefaultPolicy mirrors the original behaviour of 3 attempts roughly 2 seconds apart
+var DefaultPolicy = Policy{
+	MaxAttempts: 3,
+	BaseDelay:   2 * time.Second,
+	MaxDelay:    30 * time.Second,
+	Multiplier:  2,
+	Jitter:      0.2,
+}
+
+// Backoff returns the delay to wait after the given failed attempt (1 based)
+func (p Policy) Backoff(attempt int) time.Duration {
+	multiplier := p.Multiplier
+	if multiplier < 1 {
+		multiplier = 1
+	}
+	delay := float64(p.BaseDelay) * math.Pow(multiplier, float64(attempt-1))
+	if p.MaxDelay > 0 {
+		delay = math.Min(delay, float64(p.MaxDelay))
+	}
+	if p.Jitter > 0 {
+		delay += delay * p.Jitter * (2*rand.Float64() - 1)
+	}
+	return time.Duration(math.Max(0, delay))
+}
+
+// Do runs fn until it succeeds, returns an error that is not retryable, runs out of attempts or ctx is done.
+// Waiting between attempts gives up as soon as ctx is cancelled instead of sleeping through it.
+func (p Policy) Do(ctx context.Context, op string, fn func(ctx context.Context) error) error {
+	maxAttempts := max(p.MaxAttempts, 1)
+
+	var err error
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		if err = fn(ctx); err == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return err
+		}
+		if !IsRetryable(err) {
+			log.Printf("[retry] %s failed with a non retryable error: %v", op, err)
+			return err
+		}
+		if attempt == maxAttempts {
+			break
+		}
+
+		delay := p.Backoff(attempt)
+		log.Printf("[retry] %s attempt %d/%d failed, retrying in %s: %v", op, attempt, maxAttempts, delay, err)
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return errors.Join(err, ctx.Err())
+		case <-timer.C:
+		}
+	}
+	return fmt.Errorf("%s failed after %d attempts: %w", op, maxAttempts, err)
+}
+
+type policyKey struct{}
+
+// WithPolicy attaches the policy to ctx so operations further down use it instead of their default
+func WithPolicy(ctx context.Context, policy Policy) context.Context {
+	return context.WithValue(ctx, policyKey{}, policy)
+}
+
+// FromContext returns the policy attached to ctx, or def when there is none
+func FromContext(ctx context.Context, def Policy) Policy {
+	if policy, ok := ctx.Value(policyKey{}).(Policy); ok {
+		return policy
+	}
+	return def
+}
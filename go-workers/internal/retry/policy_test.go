@@ -0,0 +1,117 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  Policy
+		attempt int
+		want    time.Duration
+	}{
+		{name: "first attempt waits the base delay", policy: Policy{BaseDelay: time.Second, Multiplier: 2}, attempt: 1, want: time.Second},
+		{name: "grows exponentially", policy: Policy{BaseDelay: time.Second, Multiplier: 2}, attempt: 3, want: 4 * time.Second},
+		{name: "capped at the max delay", policy: Policy{BaseDelay: time.Second, MaxDelay: 5 * time.Second, Multiplier: 2}, attempt: 4, want: 5 * time.Second},
+		{name: "no cap without max delay", policy: Policy{BaseDelay: time.Second, Multiplier: 3}, attempt: 5, want: 81 * time.Second},
+		{name: "multiplier below 1 keeps the delay", policy: Policy{BaseDelay: time.Second, Multiplier: 0.5}, attempt: 4, want: time.Second},
+		{name: "zero multiplier keeps the delay", policy: Policy{BaseDelay: 200 * time.Millisecond}, attempt: 2, want: 200 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.policy.Backoff(tt.attempt); got != tt.want {
+				t.Fatalf("Backoff(%d) = %s, want %s", tt.attempt, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBackoffJitter(t *testing.T) {
+	policy := Policy{BaseDelay: 10 * time.Second, MaxDelay: 10 * time.Second, Multiplier: 2, Jitter: 0.2}
+	for attempt := 1; attempt <= 50; attempt++ {
+		got := policy.Backoff(attempt)
+		if got < 8*time.Second || got > 12*time.Second {
+			t.Fatalf("Backoff(%d) = %s, want within 20%% of 10s", attempt, got)
+		}
+	}
+}
+
+// fastPolicy retries without noticeable waits
+var fastPolicy = Policy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond, Multiplier: 1}
+
+func TestDo(t *testing.T) {
+	permanent := errors.New("permanent")
+	tests := []struct {
+		name     string
+		errs     []error
+		attempts int
+		wantErr  error
+	}{
+		{name: "succeeds at once", errs: []error{nil}, attempts: 1},
+		{name: "succeeds after transient failures", errs: []error{syscall.ECONNRESET, syscall.ECONNREFUSED, nil}, attempts: 3},
+		{name: "stops on a permanent error", errs: []error{syscall.ECONNRESET, permanent}, attempts: 2, wantErr: permanent},
+		{name: "gives up after max attempts", errs: []error{syscall.ECONNRESET, syscall.ECONNRESET, syscall.ECONNRESET, nil}, attempts: 3, wantErr: syscall.ECONNRESET},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attempts := 0
+			err := fastPolicy.Do(context.Background(), "test", func(ctx context.Context) error {
+				err := tt.errs[attempts]
+				attempts++
+				return err
+			})
+			if attempts != tt.attempts {
+				t.Errorf("ran %d attempts, want %d", attempts, tt.attempts)
+			}
+			if tt.wantErr == nil && err != nil {
+				t.Errorf("Do() = %v, want nil", err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("Do() = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDoStopsWaitingWhenCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	slow := Policy{MaxAttempts: 5, BaseDelay: time.Hour, Multiplier: 1}
+	attempts := 0
+	start := time.Now()
+	err := slow.Do(ctx, "test", func(ctx context.Context) error {
+		attempts++
+		cancel()
+		return syscall.ECONNRESET
+	})
+	if attempts != 1 || !errors.Is(err, syscall.ECONNRESET) {
+		t.Fatalf("Do() = %v after %d attempts, want the first error after 1", err, attempts)
+	}
+	if time.Since(start) > time.Second {
+		t.Fatalf("Do() slept through the cancellation")
+	}
+}
+
+func TestDoWaitInterruptedByCancel(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	slow := Policy{MaxAttempts: 5, BaseDelay: time.Hour, Multiplier: 1}
+	err := slow.Do(ctx, "test", func(context.Context) error { return syscall.ECONNRESET })
+	if !errors.Is(err, context.DeadlineExceeded) || !errors.Is(err, syscall.ECONNRESET) {
+		t.Fatalf("Do() = %v, want the last error joined with the context error", err)
+	}
+}
+
+func TestPolicyFromContext(t *testing.T) {
+	if got := FromContext(context.Background(), DefaultPolicy); got != DefaultPolicy {
+		t.Fatalf("FromContext() without a policy = %+v, want the default", got)
+	}
+	ctx := WithPolicy(context.Background(), fastPolicy)
+	if got := FromContext(ctx, DefaultPolicy); got != fastPolicy {
+		t.Fatalf("FromContext() = %+v, want %+v", got, fastPolicy)
+	}
+}
@@ -0,0 +1,91 @@
+package transformation
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"strings"
+
+	"github.com/disintegration/imaging"
+	"github.com/mahirjain10/go-workers/internal/types"
+	"github.com/mahirjain10/go-workers/internal/validation"
+)
+
+func init() {
+	Register(convertTransformer{})
+}
+
+// convertTransformer re-encodes the image in another format
+type convertTransformer struct{}
+
+func (convertTransformer) Name() string {
+	return "CONVERT"
+}
+
+func (convertTransformer) Parse(params string) (Operation, error) {
+	var convert types.Convert
+	if err := parseParams("CONVERT", params, &convert, "format"); err != nil {
+		return nil, err
+	}
+	convert.Format = strings.ToUpper(convert.Format)
+	return convertOperation(convert), nil
+}
+
+type convertOperation types.Convert
+
+func (op convertOperation) Validate(limits validation.Limits) error {
+	if _, err := targetFormat(op.Format); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (op convertOperation) Apply(buffer []byte) ([]byte, error) {
+	return Convert(buffer, op.Format)
+}
+
+// OutputExtension is the lower case target format, e.g. png
+func (op convertOperation) OutputExtension() string {
+	return strings.ToLower(op.Format)
+}
+
+// targetFormat maps the CONVERT format parameter to the imaging encoder
+func targetFormat(ext string) (imaging.Format, error) {
+	switch ext {
+	case "PNG":
+		return imaging.PNG, nil
+	case "JPEG":
+		return imaging.JPEG, nil
+	case "GIF":
+		return imaging.GIF, nil
+	case "BMP":
+		return imaging.BMP, nil
+	case "TIFF":
+		return imaging.TIFF, nil
+	case "PDF":
+		return -1, fmt.Errorf("%w: PDF conversion is not supported by pure Go libraries", ErrInvalidParameters)
+	default:
+		return -1, fmt.Errorf("%w: %s is not a supported target format", ErrInvalidParameters, ext)
+	}
+}
+
+func Convert(buffer []byte, ext string) ([]byte, error) {
+	// 1. Decode
+	img, _, err := image.Decode(bytes.NewReader(buffer))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDecode, err)
+	}
+
+	// 2. Find target format
+	format, err := targetFormat(ext)
+	if err != nil {
+		return nil, err
+	}
+
+	// 3. Encode to the new format
+	buf := new(bytes.Buffer)
+	if err = imaging.Encode(buf, img, format); err != nil {
+		return nil, fmt.Errorf("error while converting: %v", err)
+	}
+	return buf.Bytes(), nil
+}
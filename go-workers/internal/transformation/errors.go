@@ -0,0 +1,10 @@
+package transformation
+
+import "errors"
+
+var (
+	// ErrDecode is returned when the input buffer is not a decodable image
+	ErrDecode = errors.New("failed to decode image")
+	// ErrInvalidParameters is returned for parameters a transformation cannot apply
+	ErrInvalidParameters = errors.New("invalid transformation parameters")
+)
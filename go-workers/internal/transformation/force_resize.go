@@ -0,0 +1,71 @@
+package transformation
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+
+	"github.com/disintegration/imaging"
+	"github.com/mahirjain10/go-workers/internal/types"
+	"github.com/mahirjain10/go-workers/internal/validation"
+)
+
+func init() {
+	Register(forceResizeTransformer{})
+}
+
+// forceResizeTransformer stretches the image to exactly width x height
+type forceResizeTransformer struct{}
+
+func (forceResizeTransformer) Name() string {
+	return "FORCE_RESIZE"
+}
+
+func (forceResizeTransformer) Parse(params string) (Operation, error) {
+	var resize types.Resize
+	if err := parseParams("FORCE_RESIZE", params, &resize, "width", "height"); err != nil {
+		return nil, err
+	}
+	return forceResizeOperation(resize), nil
+}
+
+type forceResizeOperation types.Resize
+
+func (op forceResizeOperation) Validate(limits validation.Limits) error {
+	if op.Width <= 0 || op.Height <= 0 {
+		return fmt.Errorf("%w: width and height must be positive, got %dx%d", ErrInvalidParameters, op.Width, op.Height)
+	}
+	return limits.CheckDimensions(op.Width, op.Height)
+}
+
+func (op forceResizeOperation) Apply(buffer []byte) ([]byte, error) {
+	return ForceResize(buffer, op.Height, op.Width)
+}
+
+func (op forceResizeOperation) OutputExtension() string {
+	return ""
+}
+
+func ForceResize(buffer []byte, height int, width int) ([]byte, error) {
+	// 1. Decode
+	img, formatStr, err := image.Decode(bytes.NewReader(buffer))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDecode, err)
+	}
+
+	// 2. Get format
+	format, err := getFormat(formatStr)
+	if err != nil {
+		return nil, err
+	}
+
+	// 3. Transform: bimg.ForceResize is like imaging.Resize (stretches)
+	newImage := imaging.Resize(img, width, height, imaging.Lanczos)
+
+	// 4. Re-encode
+	buf := new(bytes.Buffer)
+	if err = imaging.Encode(buf, newImage, format); err != nil {
+		return nil, fmt.Errorf("error while force resizing: %v", err)
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,136 @@
+package transformation
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+
+	"github.com/disintegration/imaging"
+	"github.com/mahirjain10/go-workers/internal/types"
+)
+
+const (
+	histogramBins         = 256
+	histogramRenderHeight = 200
+	// Every bin is drawn two pixels wide
+	histogramRenderWidth = histogramBins * 2
+)
+
+// channelAccumulator collects the histogram and running sums of one channel
+type channelAccumulator struct {
+	bins       [histogramBins]int
+	sum        float64
+	sumSquares float64
+}
+
+func (c *channelAccumulator) add(value uint8) {
+	c.bins[value]++
+	c.sum += float64(value)
+	c.sumSquares += float64(value) * float64(value)
+}
+
+func (c *channelAccumulator) stats(count float64) types.ChannelStats {
+	stats := types.ChannelStats{Bins: c.bins[:], Min: -1}
+	for value, hits := range c.bins {
+		if hits == 0 {
+			continue
+		}
+		if stats.Min == -1 {
+			stats.Min = value
+		}
+		stats.Max = value
+	}
+	if stats.Min == -1 {
+		stats.Min = 0
+	}
+	stats.Mean = c.sum / count
+	stats.StdDev = math.Sqrt(math.Max(0, c.sumSquares/count-stats.Mean*stats.Mean))
+	return stats
+}
+
+// Histogram computes the 256-bin RGB and luminance (Rec. 601) histograms with min, max,
+// mean and standard deviation per channel. Alpha is ignored.
+func Histogram(buffer []byte) (*types.HistogramResult, error) {
+	// 1. Decode
+	img, _, err := image.Decode(bytes.NewReader(buffer))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDecode, err)
+	}
+
+	// 2. Normalise to NRGBA so every format is read the same way
+	nrgba := imaging.Clone(img)
+	width, height := nrgba.Rect.Dx(), nrgba.Rect.Dy()
+	if width == 0 || height == 0 {
+		return nil, fmt.Errorf("cannot compute histogram of an empty image")
+	}
+
+	// 3. Accumulate
+	var red, green, blue, luminance channelAccumulator
+	for y := 0; y < height; y++ {
+		row := nrgba.Pix[y*nrgba.Stride : y*nrgba.Stride+width*4]
+		for x := 0; x < width*4; x += 4 {
+			r, g, b := row[x], row[x+1], row[x+2]
+			red.add(r)
+			green.add(g)
+			blue.add(b)
+			luminance.add(uint8(math.Round(0.299*float64(r) + 0.587*float64(g) + 0.114*float64(b))))
+		}
+	}
+
+	count := float64(width * height)
+	return &types.HistogramResult{
+		Red:       red.stats(count),
+		Green:     green.stats(count),
+		Blue:      blue.stats(count),
+		Luminance: luminance.stats(count),
+	}, nil
+}
+
+// RenderHistogram draws the RGB histograms additively on a black background with the
+// luminance histogram as a gray outline on top, and encodes the result as PNG.
+func RenderHistogram(result *types.HistogramResult) ([]byte, error) {
+	// Scale every channel against the same peak so they stay comparable
+	peak := 1
+	for _, stats := range []types.ChannelStats{result.Red, result.Green, result.Blue, result.Luminance} {
+		for _, hits := range stats.Bins {
+			peak = max(peak, hits)
+		}
+	}
+	barHeight := func(stats types.ChannelStats, bin int) int {
+		return stats.Bins[bin] * histogramRenderHeight / peak
+	}
+
+	canvas := image.NewNRGBA(image.Rect(0, 0, histogramRenderWidth, histogramRenderHeight))
+	for x := 0; x < histogramRenderWidth; x++ {
+		bin := x / 2
+		redTop := histogramRenderHeight - barHeight(result.Red, bin)
+		greenTop := histogramRenderHeight - barHeight(result.Green, bin)
+		blueTop := histogramRenderHeight - barHeight(result.Blue, bin)
+		luminanceTop := histogramRenderHeight - barHeight(result.Luminance, bin)
+
+		for y := 0; y < histogramRenderHeight; y++ {
+			pixel := color.NRGBA{A: 255}
+			if y >= redTop {
+				pixel.R = 255
+			}
+			if y >= greenTop {
+				pixel.G = 255
+			}
+			if y >= blueTop {
+				pixel.B = 255
+			}
+			if y == luminanceTop || y == luminanceTop-1 {
+				pixel = color.NRGBA{R: 160, G: 160, B: 160, A: 255}
+			}
+			canvas.SetNRGBA(x, y, pixel)
+		}
+	}
+
+	buf := new(bytes.Buffer)
+	if err := imaging.Encode(buf, canvas, imaging.PNG); err != nil {
+		return nil, fmt.Errorf("error while rendering histogram: %v", err)
+	}
+	return buf.Bytes(), nil
+}
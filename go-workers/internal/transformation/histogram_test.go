@@ -0,0 +1,99 @@
+package transformation
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"testing"
+
+	"github.com/mahirjain10/go-workers/internal/types"
+)
+
+func TestHistogram(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 2, 2))
+	img.SetNRGBA(0, 0, color.NRGBA{R: 10, G: 20, B: 30, A: 255})
+	img.SetNRGBA(1, 0, color.NRGBA{R: 10, G: 20, B: 30, A: 255})
+	img.SetNRGBA(0, 1, color.NRGBA{R: 50, G: 60, B: 70, A: 255})
+	img.SetNRGBA(1, 1, color.NRGBA{R: 250, A: 255})
+
+	result, err := Histogram(encodePNG(t, img))
+	if err != nil {
+		t.Fatalf("Histogram() = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		stats    types.ChannelStats
+		bins     map[int]int
+		min, max int
+		mean     float64
+		stdDev   float64
+	}{
+		{name: "red", stats: result.Red, bins: map[int]int{10: 2, 50: 1, 250: 1}, min: 10, max: 250, mean: 80, stdDev: math.Sqrt(9900)},
+		{name: "green", stats: result.Green, bins: map[int]int{0: 1, 20: 2, 60: 1}, min: 0, max: 60, mean: 25, stdDev: math.Sqrt(475)},
+		{name: "blue", stats: result.Blue, bins: map[int]int{0: 1, 30: 2, 70: 1}, min: 0, max: 70, mean: 32.5, stdDev: math.Sqrt(618.75)},
+		// Rec. 601 luminance rounds 18.15, 58.15 and 74.75
+		{name: "luminance", stats: result.Luminance, bins: map[int]int{18: 2, 58: 1, 75: 1}, min: 18, max: 75, mean: 42.25, stdDev: math.Sqrt(624.1875)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if len(tt.stats.Bins) != histogramBins {
+				t.Fatalf("got %d bins, want %d", len(tt.stats.Bins), histogramBins)
+			}
+			for value, hits := range tt.stats.Bins {
+				if hits != tt.bins[value] {
+					t.Errorf("bin %d = %d, want %d", value, hits, tt.bins[value])
+				}
+			}
+			if tt.stats.Min != tt.min || tt.stats.Max != tt.max {
+				t.Errorf("min, max = %d, %d, want %d, %d", tt.stats.Min, tt.stats.Max, tt.min, tt.max)
+			}
+			if math.Abs(tt.stats.Mean-tt.mean) > 1e-9 || math.Abs(tt.stats.StdDev-tt.stdDev) > 1e-9 {
+				t.Errorf("mean, stdDev = %v, %v, want %v, %v", tt.stats.Mean, tt.stats.StdDev, tt.mean, tt.stdDev)
+			}
+		})
+	}
+}
+
+func TestHistogramUndecodable(t *testing.T) {
+	if _, err := Histogram([]byte("not an image")); !errors.Is(err, ErrDecode) {
+		t.Fatalf("Histogram() = %v, want ErrDecode", err)
+	}
+}
+
+func TestRenderHistogram(t *testing.T) {
+	tests := []struct {
+		name   string
+		result func(t *testing.T) *types.HistogramResult
+	}{
+		{name: "flat image", result: func(t *testing.T) *types.HistogramResult {
+			result, err := Histogram(encodePNG(t, grayImage(8, 8, flat)))
+			if err != nil {
+				t.Fatalf("Histogram() = %v", err)
+			}
+			return result
+		}},
+		{name: "empty bins", result: func(t *testing.T) *types.HistogramResult {
+			empty := types.ChannelStats{Bins: make([]int, histogramBins)}
+			return &types.HistogramResult{Red: empty, Green: empty, Blue: empty, Luminance: empty}
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rendered, err := RenderHistogram(tt.result(t))
+			if err != nil {
+				t.Fatalf("RenderHistogram() = %v", err)
+			}
+			img, err := png.Decode(bytes.NewReader(rendered))
+			if err != nil {
+				t.Fatalf("decoding render: %v", err)
+			}
+			if size := img.Bounds().Size(); size.X != histogramRenderWidth || size.Y != histogramRenderHeight {
+				t.Fatalf("render is %dx%d, want %dx%d", size.X, size.Y, histogramRenderWidth, histogramRenderHeight)
+			}
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package transformation
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+
+	"github.com/disintegration/imaging"
+	"github.com/mahirjain10/go-workers/internal/types"
+)
+
+const (
+	// Sharpness is measured on a bounded copy so the score is comparable across resolutions
+	inspectMaxEdge = 1024
+
+	// Luminance values at or beyond these are counted as clipped
+	shadowClipLevel    = 2
+	highlightClipLevel = 253
+)
+
+// Inspect scores the image sharpness (variance of the Laplacian), exposure clipping and
+// resolution and checks them against the given thresholds.
+func Inspect(buffer []byte, thresholds types.Inspect) (*types.InspectionResult, error) {
+	// 1. Decode
+	img, _, err := image.Decode(bytes.NewReader(buffer))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDecode, err)
+	}
+
+	bounds := img.Bounds()
+	result := &types.InspectionResult{
+		Width:   bounds.Dx(),
+		Height:  bounds.Dy(),
+		Reasons: []string{},
+	}
+
+	// 2. Work on a bounded grayscale copy
+	gray := imaging.Grayscale(imaging.Fit(img, inspectMaxEdge, inspectMaxEdge, imaging.Lanczos))
+
+	// 3. Score
+	result.Sharpness = laplacianVariance(gray)
+	result.ShadowClipping, result.HighlightClipping = clipping(gray)
+
+	// 4. Check thresholds
+	if result.Sharpness < thresholds.MinSharpness {
+		result.Reasons = append(result.Reasons, fmt.Sprintf("image is blurry: sharpness %.2f is below %.2f", result.Sharpness, thresholds.MinSharpness))
+	}
+	if result.ShadowClipping > thresholds.MaxClipping {
+		result.Reasons = append(result.Reasons, fmt.Sprintf("image is underexposed: %.2f%% of pixels are clipped to black", result.ShadowClipping*100))
+	}
+	if result.HighlightClipping > thresholds.MaxClipping {
+		result.Reasons = append(result.Reasons, fmt.Sprintf("image is overexposed: %.2f%% of pixels are clipped to white", result.HighlightClipping*100))
+	}
+	if result.Width < thresholds.MinWidth || result.Height < thresholds.MinHeight {
+		result.Reasons = append(result.Reasons, fmt.Sprintf("resolution %dx%d is below %dx%d", result.Width, result.Height, thresholds.MinWidth, thresholds.MinHeight))
+	}
+	result.Passed = len(result.Reasons) == 0
+	return result, nil
+}
+
+// laplacianVariance convolves the image with the 3x3 Laplacian kernel and returns the
+// variance of the response, low values mean few edges and so a blurry image.
+func laplacianVariance(gray *image.NRGBA) float64 {
+	width, height := gray.Rect.Dx(), gray.Rect.Dy()
+	if width < 3 || height < 3 {
+		return 0
+	}
+
+	luminance := func(x, y int) float64 {
+		// Grayscale copies all channels, red is enough
+		return float64(gray.Pix[y*gray.Stride+x*4])
+	}
+
+	var sum, sumSquares float64
+	count := float64((width - 2) * (height - 2))
+	for y := 1; y < height-1; y++ {
+		for x := 1; x < width-1; x++ {
+			response := luminance(x, y-1) + luminance(x-1, y) + luminance(x+1, y) + luminance(x, y+1) - 4*luminance(x, y)
+			sum += response
+			sumSquares += response * response
+		}
+	}
+	mean := sum / count
+	return sumSquares/count - mean*mean
+}
+
+// clipping returns the fraction of pixels crushed to black and blown out to white
+func clipping(gray *image.NRGBA) (float64, float64) {
+	width, height := gray.Rect.Dx(), gray.Rect.Dy()
+	if width == 0 || height == 0 {
+		return 0, 0
+	}
+
+	var shadows, highlights int
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			value := gray.Pix[y*gray.Stride+x*4]
+			if value <= shadowClipLevel {
+				shadows++
+			} else if value >= highlightClipLevel {
+				highlights++
+			}
+		}
+	}
+	total := float64(width * height)
+	return float64(shadows) / total, float64(highlights) / total
+}
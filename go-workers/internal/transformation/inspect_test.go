@@ -0,0 +1,140 @@
+package transformation
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/mahirjain10/go-workers/internal/types"
+)
+
+// grayImage returns a width x height grayscale NRGBA image with every pixel set by value
+func grayImage(width int, height int, value func(x, y int) uint8) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			v := value(x, y)
+			img.Set(x, y, color.NRGBA{R: v, G: v, B: v, A: 255})
+		}
+	}
+	return img
+}
+
+// flat, checkerboard and blown are the synthetic images the inspection tests score
+func flat(x, y int) uint8 { return 128 }
+
+func checkerboard(x, y int) uint8 {
+	if (x/4+y/4)%2 == 0 {
+		return 20
+	}
+	return 230
+}
+
+func blown(x, y int) uint8 { return 255 }
+
+func encodePNG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	buf := new(bytes.Buffer)
+	if err := png.Encode(buf, img); err != nil {
+		t.Fatalf("encoding test image: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestLaplacianVariance(t *testing.T) {
+	tests := []struct {
+		name string
+		img  *image.NRGBA
+		want float64
+	}{
+		{name: "flat", img: grayImage(16, 16, flat), want: 0},
+		{name: "too small for the kernel", img: grayImage(2, 8, checkerboard), want: 0},
+		// The interior is (1,1) and (2,1): responses -400 and 100, mean -150, variance 85000 - 22500
+		{name: "single bright pixel", img: grayImage(4, 3, func(x, y int) uint8 {
+			if x == 1 && y == 1 {
+				return 100
+			}
+			return 0
+		}), want: 62500},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := laplacianVariance(tt.img); math.Abs(got-tt.want) > 1e-9 {
+				t.Fatalf("laplacianVariance() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if sharp, blurry := laplacianVariance(grayImage(16, 16, checkerboard)), laplacianVariance(grayImage(16, 16, flat)); sharp <= blurry {
+		t.Fatalf("sharp edges scored %v, not above the flat image's %v", sharp, blurry)
+	}
+}
+
+func TestClipping(t *testing.T) {
+	tests := []struct {
+		name                string
+		img                 *image.NRGBA
+		shadows, highlights float64
+	}{
+		{name: "mid gray", img: grayImage(8, 8, flat)},
+		{name: "blown highlights", img: grayImage(8, 8, blown), highlights: 1},
+		{name: "crushed shadows", img: grayImage(8, 8, func(x, y int) uint8 { return shadowClipLevel }), shadows: 1},
+		{name: "half blown", img: grayImage(8, 8, func(x, y int) uint8 {
+			if x < 4 {
+				return highlightClipLevel
+			}
+			return 128
+		}), highlights: 0.5},
+		{name: "empty", img: image.NewNRGBA(image.Rect(0, 0, 0, 0))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shadows, highlights := clipping(tt.img)
+			if shadows != tt.shadows || highlights != tt.highlights {
+				t.Fatalf("clipping() = %v, %v, want %v, %v", shadows, highlights, tt.shadows, tt.highlights)
+			}
+		})
+	}
+}
+
+func TestInspect(t *testing.T) {
+	thresholds := types.Inspect{MinSharpness: 100, MaxClipping: 0.1, MinWidth: 32, MinHeight: 32}
+	tests := []struct {
+		name       string
+		img        *image.NRGBA
+		thresholds types.Inspect
+		reasons    []string
+	}{
+		{name: "sharp", img: grayImage(64, 64, checkerboard), thresholds: thresholds},
+		{name: "flat", img: grayImage(64, 64, flat), thresholds: thresholds, reasons: []string{"image is blurry"}},
+		{name: "blown highlights", img: grayImage(64, 64, blown), thresholds: thresholds, reasons: []string{"image is blurry", "image is overexposed"}},
+		{name: "too small", img: grayImage(16, 64, checkerboard), thresholds: thresholds, reasons: []string{"resolution 16x64 is below 32x32"}},
+		{name: "thresholds of zero", img: grayImage(64, 64, flat), thresholds: types.Inspect{}},
+		{name: "no clipping tolerated", img: grayImage(64, 64, func(x, y int) uint8 {
+			if x == 0 && y == 0 {
+				return 255
+			}
+			return checkerboard(x, y)
+		}), thresholds: types.Inspect{MinSharpness: 100}, reasons: []string{"image is overexposed"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := Inspect(encodePNG(t, tt.img), tt.thresholds)
+			if err != nil {
+				t.Fatalf("Inspect() = %v", err)
+			}
+			if result.Passed != (len(tt.reasons) == 0) || len(result.Reasons) != len(tt.reasons) {
+				t.Fatalf("Inspect() passed %t with reasons %q, want %q", result.Passed, result.Reasons, tt.reasons)
+			}
+			for i, reason := range tt.reasons {
+				if !strings.HasPrefix(result.Reasons[i], reason) {
+					t.Errorf("reason %d = %q, want it to start with %q", i, result.Reasons[i], reason)
+				}
+			}
+		})
+	}
+}
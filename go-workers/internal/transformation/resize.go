@@ -0,0 +1,71 @@
+package transformation
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+
+	"github.com/disintegration/imaging"
+	"github.com/mahirjain10/go-workers/internal/types"
+	"github.com/mahirjain10/go-workers/internal/validation"
+)
+
+func init() {
+	Register(resizeTransformer{})
+}
+
+// resizeTransformer fits the image within width x height, keeping its aspect ratio
+type resizeTransformer struct{}
+
+func (resizeTransformer) Name() string {
+	return "RESIZE"
+}
+
+func (resizeTransformer) Parse(params string) (Operation, error) {
+	var resize types.Resize
+	if err := parseParams("RESIZE", params, &resize, "width", "height"); err != nil {
+		return nil, err
+	}
+	return resizeOperation(resize), nil
+}
+
+type resizeOperation types.Resize
+
+func (op resizeOperation) Validate(limits validation.Limits) error {
+	if op.Width <= 0 || op.Height <= 0 {
+		return fmt.Errorf("%w: width and height must be positive, got %dx%d", ErrInvalidParameters, op.Width, op.Height)
+	}
+	return limits.CheckDimensions(op.Width, op.Height)
+}
+
+func (op resizeOperation) Apply(buffer []byte) ([]byte, error) {
+	return Resize(buffer, op.Height, op.Width)
+}
+
+func (op resizeOperation) OutputExtension() string {
+	return ""
+}
+
+func Resize(buffer []byte, height int, width int) ([]byte, error) {
+	// 1. Decode the image
+	img, formatStr, err := image.Decode(bytes.NewReader(buffer))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDecode, err)
+	}
+
+	// 2. Get the original format for re-encoding
+	format, err := getFormat(formatStr)
+	if err != nil {
+		return nil, err
+	}
+
+	// 3. Transform: bimg.Resize is like imaging.Thumbnail (fits within box)
+	newImage := imaging.Thumbnail(img, width, height, imaging.Lanczos)
+
+	// 4. Re-encode to a new buffer
+	buf := new(bytes.Buffer)
+	if err = imaging.Encode(buf, newImage, format); err != nil {
+		return nil, fmt.Errorf("error while resizing: %v", err)
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,87 @@
+package transformation
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+
+	"github.com/disintegration/imaging"
+	"github.com/mahirjain10/go-workers/internal/types"
+	"github.com/mahirjain10/go-workers/internal/validation"
+)
+
+func init() {
+	Register(rotateTransformer{})
+}
+
+// rotateTransformer rotates the image counter-clockwise by a multiple of 90 degrees
+type rotateTransformer struct{}
+
+func (rotateTransformer) Name() string {
+	return "ROTATE"
+}
+
+func (rotateTransformer) Parse(params string) (Operation, error) {
+	var rotate types.Rotate
+	if err := parseParams("ROTATE", params, &rotate, "degree"); err != nil {
+		return nil, err
+	}
+	return rotateOperation(rotate), nil
+}
+
+type rotateOperation types.Rotate
+
+func (op rotateOperation) Validate(limits validation.Limits) error {
+	switch op.Degree {
+	case 0, 90, 180, 270:
+		return nil
+	default:
+		return fmt.Errorf("%w: unsupported angle: %d. Only 0, 90, 180, 270 supported", ErrInvalidParameters, op.Degree)
+	}
+}
+
+func (op rotateOperation) Apply(buffer []byte) ([]byte, error) {
+	return Rotate(buffer, op.Degree)
+}
+
+func (op rotateOperation) OutputExtension() string {
+	return ""
+}
+
+func Rotate(buffer []byte, degree int) ([]byte, error) {
+	// 1. Decode
+	img, formatStr, err := image.Decode(bytes.NewReader(buffer))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDecode, err)
+	}
+
+	// 2. Get format
+	format, err := getFormat(formatStr)
+	if err != nil {
+		return nil, err
+	}
+
+	// 3. Transform
+	var newImage image.Image
+	switch degree {
+	case 90:
+		newImage = imaging.Rotate90(img)
+	case 180:
+		newImage = imaging.Rotate180(img)
+	case 270:
+		newImage = imaging.Rotate270(img)
+	case 0:
+		newImage = img
+	default:
+		// bimg only supports 90, 180, 270. imaging.Rotate() can do any angle,
+		// but we'll stick to the original logic.
+		return nil, fmt.Errorf("%w: unsupported angle: %d. Only 0, 90, 180, 270 supported", ErrInvalidParameters, degree)
+	}
+
+	// 4. Re-encode
+	buf := new(bytes.Buffer)
+	if err = imaging.Encode(buf, newImage, format); err != nil {
+		return nil, fmt.Errorf("error while rotating: %v", err)
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,83 @@
+package transformation
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/mahirjain10/go-workers/internal/utils"
+	"github.com/mahirjain10/go-workers/internal/validation"
+)
+
+// Transformer is one image operation a job can ask for by its TransformationType. Adding an operation means
+// a new file with its Transformer registered from init, the worker routes it from then on.
+type Transformer interface {
+	// Name is the TransformationType the operation answers to, e.g. RESIZE
+	Name() string
+	// Parse decodes the job's transformationParameters, malformed ones wrap ErrInvalidParameters
+	Parse(params string) (Operation, error)
+}
+
+// Operation is a parsed transformation, ready to run on an image
+type Operation interface {
+	// Validate checks the parameters against the worker's limits before the image is even read
+	Validate(limits validation.Limits) error
+	// Apply runs the operation on the encoded source image and returns the encoded result
+	Apply(buffer []byte) ([]byte, error)
+	// OutputExtension is the extension of the result without the dot, "" keeps the source's
+	OutputExtension() string
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]Transformer)
+)
+
+// Register makes a transformer routable, registering the same name twice is a programming error
+func Register(transformer Transformer) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if _, ok := registry[transformer.Name()]; ok {
+		panic(fmt.Sprintf("transformation: %s registered twice", transformer.Name()))
+	}
+	registry[transformer.Name()] = transformer
+}
+
+// Lookup returns the transformer of a TransformationType
+func Lookup(name string) (Transformer, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	transformer, ok := registry[name]
+	return transformer, ok
+}
+
+// Names lists the registered TransformationTypes, sorted
+func Names() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// parseParams strictly decodes JSON parameters into target, unknown and missing required fields are
+// reported as invalid parameters so a forgotten height never turns into a resize to 0
+func parseParams(name string, params string, target any, required ...string) error {
+	if err := utils.ParseStrictJSON([]byte(params), target); err != nil {
+		return fmt.Errorf("%w: %s parameters: %v", ErrInvalidParameters, name, err)
+	}
+	var present map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(params), &present); err != nil {
+		return fmt.Errorf("%w: %s parameters: %v", ErrInvalidParameters, name, err)
+	}
+	for _, field := range required {
+		if value, ok := present[field]; !ok || string(value) == "null" {
+			return fmt.Errorf("%w: %s parameters: %s is required", ErrInvalidParameters, name, field)
+		}
+	}
+	return nil
+}
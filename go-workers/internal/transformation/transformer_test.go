@@ -0,0 +1,200 @@
+package transformation
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"slices"
+	"testing"
+
+	"github.com/mahirjain10/go-workers/internal/validation"
+)
+
+func TestRegistryHasBuiltinTransformers(t *testing.T) {
+	want := []string{"CONVERT", "FORCE_RESIZE", "RESIZE", "ROTATE"}
+	if got := Names(); !slices.Equal(got, want) {
+		t.Fatalf("Names() = %v, want %v", got, want)
+	}
+	for _, name := range want {
+		transformer, ok := Lookup(name)
+		if !ok {
+			t.Fatalf("Lookup(%q) not found", name)
+		}
+		if transformer.Name() != name {
+			t.Errorf("Lookup(%q).Name() = %q", name, transformer.Name())
+		}
+	}
+}
+
+func TestLookupUnknown(t *testing.T) {
+	if _, ok := Lookup("SHARPEN"); ok {
+		t.Fatal("Lookup(SHARPEN) found a transformer")
+	}
+}
+
+func TestRegisterTwicePanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("registering RESIZE twice did not panic")
+		}
+	}()
+	Register(resizeTransformer{})
+}
+
+func TestParseMalformedParams(t *testing.T) {
+	for _, name := range Names() {
+		transformer, _ := Lookup(name)
+		if _, err := transformer.Parse("{not json"); !errors.Is(err, ErrInvalidParameters) {
+			t.Errorf("%s: Parse error = %v, want ErrInvalidParameters", name, err)
+		}
+	}
+}
+
+// testPNG encodes a width x height PNG in memory
+func testPNG(t *testing.T, width int, height int) []byte {
+	t.Helper()
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, color.NRGBA{R: uint8(x), G: uint8(y), B: 128, A: 255})
+		}
+	}
+	buf := new(bytes.Buffer)
+	if err := png.Encode(buf, img); err != nil {
+		t.Fatalf("encoding test image: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// mustParse looks up a registered transformer and parses params with it
+func mustParse(t *testing.T, name string, params string) Operation {
+	t.Helper()
+	transformer, ok := Lookup(name)
+	if !ok {
+		t.Fatalf("%s is not registered", name)
+	}
+	operation, err := transformer.Parse(params)
+	if err != nil {
+		t.Fatalf("parsing %s: %v", params, err)
+	}
+	return operation
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		params string
+		limits validation.Limits
+		want   error
+	}{
+		{name: "RESIZE", params: `{"width":40,"height":20}`},
+		{name: "RESIZE", params: `{"width":0,"height":20}`, want: ErrInvalidParameters},
+		{name: "RESIZE", params: `{"width":400,"height":400}`, limits: validation.Limits{MaxPixels: 1000}, want: validation.ErrImageTooLarge},
+		{name: "FORCE_RESIZE", params: `{"width":-5,"height":70}`, want: ErrInvalidParameters},
+		{name: "ROTATE", params: `{"degree":270}`},
+		{name: "ROTATE", params: `{"degree":45}`, want: ErrInvalidParameters},
+		{name: "ROTATE", params: `{"degree":360}`, want: ErrInvalidParameters},
+		{name: "CONVERT", params: `{"format":"jpeg"}`},
+		{name: "CONVERT", params: `{"format":"WEBP"}`, want: ErrInvalidParameters},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name+" "+tt.params, func(t *testing.T) {
+			err := mustParse(t, tt.name, tt.params).Validate(tt.limits)
+			if tt.want == nil && err != nil {
+				t.Fatalf("Validate() = %v, want nil", err)
+			}
+			if tt.want != nil && !errors.Is(err, tt.want) {
+				t.Fatalf("Validate() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestApply(t *testing.T) {
+	tests := []struct {
+		name          string
+		params        string
+		width, height int
+		format        string
+		extension     string
+	}{
+		{name: "RESIZE", params: `{"width":40,"height":20}`, width: 40, height: 20, format: "png"},
+		{name: "FORCE_RESIZE", params: `{"width":30,"height":70}`, width: 30, height: 70, format: "png"},
+		{name: "ROTATE", params: `{"degree":90}`, width: 40, height: 80, format: "png"},
+		{name: "ROTATE", params: `{"degree":180}`, width: 80, height: 40, format: "png"},
+		{name: "CONVERT", params: `{"format":"JPEG"}`, width: 80, height: 40, format: "jpeg", extension: "jpeg"},
+		{name: "CONVERT", params: `{"format":"gif"}`, width: 80, height: 40, format: "gif", extension: "gif"},
+		{name: "CONVERT", params: `{"format":"BMP"}`, width: 80, height: 40, format: "bmp", extension: "bmp"},
+		{name: "CONVERT", params: `{"format":"TIFF"}`, width: 80, height: 40, format: "tiff", extension: "tiff"},
+	}
+	source := testPNG(t, 80, 40)
+	for _, tt := range tests {
+		t.Run(tt.name+" "+tt.params, func(t *testing.T) {
+			operation := mustParse(t, tt.name, tt.params)
+			out, err := operation.Apply(source)
+			if err != nil {
+				t.Fatalf("Apply() = %v", err)
+			}
+			cfg, format, err := image.DecodeConfig(bytes.NewReader(out))
+			if err != nil {
+				t.Fatalf("decoding result: %v", err)
+			}
+			if cfg.Width != tt.width || cfg.Height != tt.height || format != tt.format {
+				t.Errorf("result is a %dx%d %s, want a %dx%d %s", cfg.Width, cfg.Height, format, tt.width, tt.height, tt.format)
+			}
+			if ext := operation.OutputExtension(); ext != tt.extension {
+				t.Errorf("OutputExtension() = %q, want %q", ext, tt.extension)
+			}
+		})
+	}
+}
+
+func TestApplyUndecodable(t *testing.T) {
+	params := map[string]string{
+		"RESIZE":       `{"width":40,"height":20}`,
+		"FORCE_RESIZE": `{"width":30,"height":70}`,
+		"ROTATE":       `{"degree":90}`,
+		"CONVERT":      `{"format":"PNG"}`,
+	}
+	for _, name := range Names() {
+		if _, err := mustParse(t, name, params[name]).Apply([]byte("not an image")); !errors.Is(err, ErrDecode) {
+			t.Errorf("%s: Apply() = %v, want ErrDecode", name, err)
+		}
+	}
+}
+
+func TestRotateApplyUnsupportedAngle(t *testing.T) {
+	_, err := mustParse(t, "ROTATE", `{"degree":45}`).Apply(testPNG(t, 8, 8))
+	if !errors.Is(err, ErrInvalidParameters) {
+		t.Fatalf("Apply() = %v, want ErrInvalidParameters", err)
+	}
+}
+
+func TestParseRejectsIncompleteOrUnknownParams(t *testing.T) {
+	tests := []struct {
+		name   string
+		params string
+	}{
+		{name: "RESIZE", params: `{"width":40}`},
+		{name: "RESIZE", params: `{"width":40,"height":null}`},
+		{name: "RESIZE", params: `{"width":40,"height":20,"crop":true}`},
+		{name: "FORCE_RESIZE", params: `{"height":20}`},
+		{name: "FORCE_RESIZE", params: `"{\"width\":40,\"height\":20}"`},
+		{name: "ROTATE", params: `{}`},
+		{name: "ROTATE", params: `{"degree":90}{"degree":180}`},
+		{name: "ROTATE", params: `{"degrees":90}`},
+		{name: "CONVERT", params: `{}`},
+		{name: "CONVERT", params: `{"format":"PNG","quality":80}`},
+		{name: "CONVERT", params: ``},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name+" "+tt.params, func(t *testing.T) {
+			transformer, _ := Lookup(tt.name)
+			if _, err := transformer.Parse(tt.params); !errors.Is(err, ErrInvalidParameters) {
+				t.Fatalf("Parse error = %v, want ErrInvalidParameters", err)
+			}
+		})
+	}
+}
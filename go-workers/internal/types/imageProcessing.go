@@ -11,6 +11,8 @@ type ImageProcessing struct {
 	TransformationParameters string `json:"transformationParameters"`
 	S3PublicUrl              string `json:"s3PublicUrl"`
 	CreatedAt                string `json:"createdAt"`
+	// Priority orders jobs on a priority queue, higher first, capped at QUEUE_MAX_PRIORITY
+	Priority int `json:"priority,omitempty"`
 }
 
 type Resize struct {
@@ -25,3 +27,71 @@ type Rotate struct {
 type Convert struct {
 	Format string `json:"format"`
 }
+
+// Inspect holds the quality thresholds an INSPECT job is checked against
+type Inspect struct {
+	MinSharpness float64 `json:"minSharpness"`
+	MaxClipping  float64 `json:"maxClipping"`
+	MinWidth     int     `json:"minWidth"`
+	MinHeight    int     `json:"minHeight"`
+}
+
+// InspectParameters are the thresholds an INSPECT job sends, a nil field falls back to the worker default
+// while an explicit zero is kept, e.g. a maxClipping of 0 tolerates no clipped pixel at all
+type InspectParameters struct {
+	MinSharpness *float64 `json:"minSharpness"`
+	MaxClipping  *float64 `json:"maxClipping"`
+	MinWidth     *int     `json:"minWidth"`
+	MinHeight    *int     `json:"minHeight"`
+}
+
+// Thresholds returns defaults overridden by every threshold the job set
+func (p InspectParameters) Thresholds(defaults Inspect) Inspect {
+	thresholds := defaults
+	if p.MinSharpness != nil {
+		thresholds.MinSharpness = *p.MinSharpness
+	}
+	if p.MaxClipping != nil {
+		thresholds.MaxClipping = *p.MaxClipping
+	}
+	if p.MinWidth != nil {
+		thresholds.MinWidth = *p.MinWidth
+	}
+	if p.MinHeight != nil {
+		thresholds.MinHeight = *p.MinHeight
+	}
+	return thresholds
+}
+
+// InspectionResult is the outcome of an INSPECT job, Reasons lists every failed check
+type InspectionResult struct {
+	Passed            bool     `json:"passed"`
+	Sharpness         float64  `json:"sharpness"`
+	ShadowClipping    float64  `json:"shadowClipping"`
+	HighlightClipping float64  `json:"highlightClipping"`
+	Width             int      `json:"width"`
+	Height            int      `json:"height"`
+	Reasons           []string `json:"reasons"`
+}
+
+// Histogram holds the parameters of a HISTOGRAM job, Render also uploads a PNG of the histogram
+type Histogram struct {
+	Render bool `json:"render"`
+}
+
+// ChannelStats is the 256-bin histogram and the summary statistics of one channel
+type ChannelStats struct {
+	Bins   []int   `json:"bins"`
+	Min    int     `json:"min"`
+	Max    int     `json:"max"`
+	Mean   float64 `json:"mean"`
+	StdDev float64 `json:"stdDev"`
+}
+
+// HistogramResult is the outcome of a HISTOGRAM job
+type HistogramResult struct {
+	Red       ChannelStats `json:"red"`
+	Green     ChannelStats `json:"green"`
+	Blue      ChannelStats `json:"blue"`
+	Luminance ChannelStats `json:"luminance"`
+}
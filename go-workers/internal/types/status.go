@@ -6,7 +6,16 @@ type StatusData struct {
 	UserID    string `json:"userId"`
 	Status    string `json:"status"`
 	PublicURL string `json:"publicUrl"`
-	ErrorMsg string `json:"errorMsg"`
+	ErrorMsg  string `json:"errorMsg"`
+	// ErrorCode is the stable code of a FAILED status, see internal/queue/errors
+	ErrorCode string `json:"errorCode,omitempty"`
+	// Placeholders are only set on PROCESSED events when placeholder generation is enabled
+	BlurHash string `json:"blurHash,omitempty"`
+	Lqip     string `json:"lqip,omitempty"`
+	// Inspection is only set for INSPECT jobs
+	Inspection *InspectionResult `json:"inspection,omitempty"`
+	// Histogram is only set for HISTOGRAM jobs
+	Histogram *HistogramResult `json:"histogram,omitempty"`
 }
 
 // StatusMessage represents the full message envelope
@@ -15,7 +24,8 @@ type StatusMessage struct {
 	Data    StatusData `json:"data"`
 }
 
-
 const PROCCESSED = "PROCESSED"
 const FAILED = "FAILED"
-const PROCESSING = "PROCESSING"
\ No newline at end of file
+const PROCESSING = "PROCESSING"
+const CANCELLED = "CANCELLED"
+const EXPIRED = "EXPIRED"
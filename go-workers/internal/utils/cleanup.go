@@ -44,6 +44,19 @@ func RemoveLocalProcessed(uploadPath, s3RawKey string) error {
 	return nil
 }
 
+// RemoveLocalFile removes the local file at basePath + key, used for outputs not derived from the raw key.
+func RemoveLocalFile(basePath, key string) error {
+	fp, err := PathUtil(basePath, key)
+	if err != nil {
+		return fmt.Errorf("construct file path: %w", err)
+	}
+	if err := os.Remove(fp); err != nil {
+		return fmt.Errorf("remove file %q: %w", fp, err)
+	}
+	log.Printf("removed local file: %s", fp)
+	return nil
+}
+
 func DeleteS3Object(ctx context.Context, s3 S3Deleter, s3Key string) error {
 	if _, err := s3.DeleteS3Object(ctx, s3Key); err != nil {
 		return fmt.Errorf("delete s3 object %q: %w", s3Key, err)
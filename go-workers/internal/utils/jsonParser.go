@@ -1,6 +1,7 @@
 package utils
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 )
@@ -12,6 +13,19 @@ func ParseJSON(data []byte, v any) error {
 	return nil
 }
 
+// ParseStrictJSON is ParseJSON for messages with a schema: unknown fields and trailing data are errors
+func ParseStrictJSON(data []byte, v any) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		return fmt.Errorf("failed to parse JSON: %w", err)
+	}
+	if decoder.More() {
+		return fmt.Errorf("failed to parse JSON: unexpected data after the top-level value")
+	}
+	return nil
+}
+
 func SerializeJSON(data interface{}) ([]byte, error) {
 	value, err := json.Marshal(data)
 	if err != nil {
@@ -1,8 +1,9 @@
 package utils
 
 import (
+	"context"
 	"fmt"
-	"strings"
+	"time"
 
 	amqp "github.com/rabbitmq/amqp091-go"
 )
@@ -27,49 +28,238 @@ func NewChannel(conn *amqp.Connection) (*amqp.Channel, error) {
 
 // ─── QUEUE OPERATIONS ─────────────────────────────────────────────────────
 
-func NewQueue(ch *amqp.Channel, queueName string) (*amqp.Queue, error) {
-	queue, err := ch.QueueDeclare(queueName, true, false, false, false, nil)
+// NewQueue declares a durable queue, a maxPriority above 0 makes it a priority queue. RabbitMQ refuses to
+// redeclare an existing queue with a different x-max-priority, so changing it means recreating the queue.
+func NewQueue(ch *amqp.Channel, queueName string, maxPriority uint8) (*amqp.Queue, error) {
+	var args amqp.Table
+	if maxPriority > 0 {
+		args = amqp.Table{"x-max-priority": int32(maxPriority)}
+	}
+	queue, err := ch.QueueDeclare(queueName, true, false, false, false, args)
 	if err != nil {
 		return nil, fmt.Errorf("failed to start channel : %v", err)
 	}
 	return &queue, nil
 }
 
-func NewQueueConsumer(ch *amqp.Channel, queueName string) (<-chan amqp.Delivery, error) {
-	msgs, err := ch.Consume(queueName, "", false, false, false, false, nil)
+// ClampPriority bounds a requested job priority to what the queue supports
+func ClampPriority(priority int, maxPriority uint8) uint8 {
+	return uint8(min(max(priority, 0), int(maxPriority)))
+}
+
+// DeclareJobsExchange declares the topic exchange jobs are published to in the topic routing mode
+func DeclareJobsExchange(ch *amqp.Channel, exchange string) error {
+	if err := ch.ExchangeDeclare(exchange, "topic", true, false, false, false, nil); err != nil {
+		return fmt.Errorf("failed to declare jobs exchange : %v", err)
+	}
+	return nil
+}
+
+// ─── CONTROL MESSAGES ─────────────────────────────────────────────────────
+
+// ControlExchange fans control messages such as job cancels out to every worker replica
+const ControlExchange = "image_processing.control"
+
+// DeclareControlExchange declares the fanout exchange control messages are published to
+func DeclareControlExchange(ch *amqp.Channel) error {
+	if err := ch.ExchangeDeclare(ControlExchange, "fanout", true, false, false, false, nil); err != nil {
+		return fmt.Errorf("failed to declare control exchange : %v", err)
+	}
+	return nil
+}
+
+// NewControlConsumer consumes control messages on a queue of its own, exclusive to this connection and
+// deleted with it, so every replica sees every message
+func NewControlConsumer(ch *amqp.Channel) (<-chan amqp.Delivery, error) {
+	queue, err := ch.QueueDeclare("", false, true, true, false, nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to consume : %v", err)
+		return nil, fmt.Errorf("failed to declare control queue : %v", err)
+	}
+	if err := ch.QueueBind(queue.Name, "", ControlExchange, false, nil); err != nil {
+		return nil, fmt.Errorf("failed to bind control queue : %v", err)
+	}
+	msgs, err := ch.Consume(queue.Name, "", true, true, false, false, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to consume control queue : %v", err)
+	}
+	return msgs, nil
+}
+
+// CancelQueue keeps the cancels published to ControlExchange while no worker is connected, they expire
+// after the ttl it was declared with
+const CancelQueue = "image_processing.cancels"
+
+// DeclareCancelQueue declares the durable CancelQueue and binds it to ControlExchange. Workers share it,
+// each cancel is consumed by one of them and recorded where every replica looks it up.
+func DeclareCancelQueue(ch *amqp.Channel, ttl time.Duration) error {
+	if _, err := ch.QueueDeclare(CancelQueue, true, false, false, false, amqp.Table{
+		"x-message-ttl": ttl.Milliseconds(),
+	}); err != nil {
+		return fmt.Errorf("failed to declare %s : %v", CancelQueue, err)
+	}
+	if err := ch.QueueBind(CancelQueue, "", ControlExchange, false, nil); err != nil {
+		return fmt.Errorf("failed to bind %s : %v", CancelQueue, err)
+	}
+	return nil
+}
+
+// NewCancelQueueConsumer consumes CancelQueue, deliveries must be acked once the cancel is recorded
+func NewCancelQueueConsumer(ch *amqp.Channel) (<-chan amqp.Delivery, error) {
+	msgs, err := ch.Consume(CancelQueue, "", false, false, false, false, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to consume %s : %v", CancelQueue, err)
 	}
 	return msgs, nil
 }
 
-// ─── ERROR CLASSIFICATION ─────────────────────────────────────────────────
+// ─── DEAD LETTER AND RETRY TOPOLOGY ───────────────────────────────────────
+
+// DeadLetterExchange receives every message a worker gave up on, routed by its work queue name
+const DeadLetterExchange = "image_processing.dlx"
+
+// Headers carried by dead-lettered and retried messages
+const (
+	HeaderAttempt       = "x-attempt"
+	HeaderFailureReason = "x-failure-reason"
+	HeaderErrorCode     = "x-error-code"
+	HeaderFailedQueue   = "x-failed-queue"
+	HeaderFailedAt      = "x-failed-at"
+	HeaderDeferred      = "x-deferred"
+)
+
+func DeadLetterQueueName(queueName string) string {
+	return queueName + ".dlq"
+}
+
+// RetryQueueName names the TTL queue of a retry tier, e.g. resize_queue.retry.10s
+func RetryQueueName(queueName string, delay time.Duration) string {
+	return delayQueueName(queueName, "retry", delay)
+}
+
+// DeferQueueName names the TTL queue jobs over their user's limits wait in, e.g. resize_queue.deferred.5s
+func DeferQueueName(queueName string, delay time.Duration) string {
+	return delayQueueName(queueName, "deferred", delay)
+}
 
-func IsTransientError(err error) bool {
-	if strings.Contains(err.Error(), "timeout") || strings.Contains(err.Error(), "connection reset") {
-		return true
+func delayQueueName(queueName string, kind string, delay time.Duration) string {
+	var suffix string
+	switch {
+	case delay%time.Hour == 0:
+		suffix = fmt.Sprintf("%dh", delay/time.Hour)
+	case delay%time.Minute == 0:
+		suffix = fmt.Sprintf("%dm", delay/time.Minute)
+	case delay%time.Second == 0:
+		suffix = fmt.Sprintf("%ds", delay/time.Second)
+	default:
+		suffix = fmt.Sprintf("%dms", delay/time.Millisecond)
 	}
-	return false
+	return fmt.Sprintf("%s.%s.%s", queueName, kind, suffix)
 }
 
-func IsFatalError(err error) bool {
-	// Infrastructure failures that should stop the worker
-	errorStr := strings.ToLower(err.Error())
-	
-	// RabbitMQ connection issues
-	if strings.Contains(errorStr, "connection closed") || strings.Contains(errorStr, "channel closed") {
-		return true
+// DeclareDeadLetterQueue declares the shared dead letter exchange and the <queue>.dlq bound to it
+func DeclareDeadLetterQueue(ch *amqp.Channel, queueName string) error {
+	if err := ch.ExchangeDeclare(DeadLetterExchange, "direct", true, false, false, false, nil); err != nil {
+		return fmt.Errorf("failed to declare dead letter exchange : %v", err)
 	}
-	
-	// AWS authentication issues
-	if strings.Contains(errorStr, "invalid credentials") || strings.Contains(errorStr, "access denied") {
-		return true
+	dlq := DeadLetterQueueName(queueName)
+	if _, err := ch.QueueDeclare(dlq, true, false, false, false, nil); err != nil {
+		return fmt.Errorf("failed to declare %s : %v", dlq, err)
 	}
-	
-	// System resource issues
-	if strings.Contains(errorStr, "no space left") || strings.Contains(errorStr, "out of memory") {
-		return true
+	if err := ch.QueueBind(dlq, queueName, DeadLetterExchange, false, nil); err != nil {
+		return fmt.Errorf("failed to bind %s : %v", dlq, err)
 	}
-	
-	return false
-}
\ No newline at end of file
+	return nil
+}
+
+// DeclareRetryQueues declares one TTL queue per delay. Nothing consumes them, once the TTL expires
+// RabbitMQ dead-letters the message through the default exchange back onto the work queue.
+func DeclareRetryQueues(ch *amqp.Channel, queueName string, delays []time.Duration) error {
+	for _, delay := range delays {
+		if err := declareDelayQueue(ch, RetryQueueName(queueName, delay), delay, queueName); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeclareDeferQueue declares the TTL queue deferred jobs wait in before going back onto the work queue
+func DeclareDeferQueue(ch *amqp.Channel, queueName string, delay time.Duration) error {
+	return declareDelayQueue(ch, DeferQueueName(queueName, delay), delay, queueName)
+}
+
+func declareDelayQueue(ch *amqp.Channel, delayQueue string, delay time.Duration, target string) error {
+	_, err := ch.QueueDeclare(delayQueue, true, false, false, false, amqp.Table{
+		"x-message-ttl":             delay.Milliseconds(),
+		"x-dead-letter-exchange":    "",
+		"x-dead-letter-routing-key": target,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to declare %s : %v", delayQueue, err)
+	}
+	return nil
+}
+
+// AttemptCount reads the attempt header, a message that was never retried is attempt 0
+func AttemptCount(d amqp.Delivery) int {
+	switch attempt := d.Headers[HeaderAttempt].(type) {
+	case int8:
+		return int(attempt)
+	case int16:
+		return int(attempt)
+	case int32:
+		return int(attempt)
+	case int64:
+		return int(attempt)
+	default:
+		return 0
+	}
+}
+
+// NewQueueConsumer starts consuming queueName with at most prefetch unacked messages on ch, the consumer
+// tag is what Channel.Cancel needs to stop it. ch is put in confirm mode for PublishConfirmed.
+func NewQueueConsumer(ch *amqp.Channel, queueName string, consumerTag string, prefetch int) (<-chan amqp.Delivery, error) {
+	if err := ch.Qos(prefetch, 0, false); err != nil {
+		return nil, fmt.Errorf("failed to set prefetch : %v", err)
+	}
+	if err := ch.Confirm(false); err != nil {
+		return nil, fmt.Errorf("failed to enable publisher confirms : %v", err)
+	}
+	msgs, err := ch.Consume(queueName, consumerTag, false, false, false, false, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to consume : %v", err)
+	}
+	return msgs, nil
+}
+
+// PublishConfirmed publishes on a channel in confirm mode and waits for the broker to take the message.
+// A consumer moving a delivery elsewhere (retry, dead letter, deferred queue) acks the original only
+// after this succeeded, so a lost publish never loses the job.
+func PublishConfirmed(ctx context.Context, ch *amqp.Channel, exchange string, routingKey string, msg amqp.Publishing) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	confirmation, err := ch.PublishWithDeferredConfirmWithContext(ctx, exchange, routingKey, false, false, msg)
+	if err != nil {
+		return fmt.Errorf("failed to publish : %v", err)
+	}
+	if confirmation == nil {
+		return fmt.Errorf("failed to publish : channel is not in confirm mode")
+	}
+	acked, err := confirmation.WaitContext(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to confirm publish : %w", err)
+	}
+	if !acked {
+		return fmt.Errorf("failed to publish : broker nacked the message")
+	}
+	return nil
+}
+
+// QueueDepth returns the number of ready messages of queueName through a passive declare. A missing queue
+// closes ch, callers need a fresh channel after an error.
+func QueueDepth(ch *amqp.Channel, queueName string) (int, error) {
+	queue, err := ch.QueueDeclarePassive(queueName, true, false, false, false, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to inspect %s : %v", queueName, err)
+	}
+	return queue.Messages, nil
+}
@@ -0,0 +1,132 @@
+package validation
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+
+	// Registering the decoders for image.DecodeConfig
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+
+	_ "golang.org/x/image/bmp"
+	_ "golang.org/x/image/tiff"
+)
+
+// ErrImageTooLarge is returned when an image would exceed the configured processing limits
+var ErrImageTooLarge = errors.New("image exceeds processing limits")
+
+// Limits bounds what the worker is willing to decode, zero disables a limit
+type Limits struct {
+	MaxPixels int64
+	MaxBytes  int64
+	MaxFrames int
+}
+
+// CheckSize rejects files larger than MaxBytes before they are read into memory
+func (l Limits) CheckSize(size int64) error {
+	if l.MaxBytes > 0 && size > l.MaxBytes {
+		return fmt.Errorf("%w: file is %d bytes, max is %d", ErrImageTooLarge, size, l.MaxBytes)
+	}
+	return nil
+}
+
+// CheckDimensions rejects a width x height canvas larger than MaxPixels
+func (l Limits) CheckDimensions(width int, height int) error {
+	if width < 0 || height < 0 {
+		return fmt.Errorf("invalid dimensions %dx%d", width, height)
+	}
+	if l.MaxPixels > 0 && int64(width)*int64(height) > l.MaxPixels {
+		return fmt.Errorf("%w: %dx%d is more than %d pixels", ErrImageTooLarge, width, height, l.MaxPixels)
+	}
+	return nil
+}
+
+// CheckImage reads only the image header with image.DecodeConfig and enforces every limit, so a
+// small file declaring a huge canvas is rejected before anything allocates the decoded pixels.
+func (l Limits) CheckImage(buffer []byte, format string) (image.Config, error) {
+	if err := l.CheckSize(int64(len(buffer))); err != nil {
+		return image.Config{}, err
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(buffer))
+	if err != nil {
+		return image.Config{}, fmt.Errorf("failed to decode image config: %v", err)
+	}
+	if err := l.CheckDimensions(cfg.Width, cfg.Height); err != nil {
+		return image.Config{}, err
+	}
+
+	if format == FormatGIF && l.MaxFrames > 0 {
+		frames, err := countGIFFrames(buffer)
+		if err != nil {
+			return image.Config{}, err
+		}
+		if frames > l.MaxFrames {
+			return image.Config{}, fmt.Errorf("%w: %d frames, max is %d", ErrImageTooLarge, frames, l.MaxFrames)
+		}
+	}
+	return cfg, nil
+}
+
+// countGIFFrames walks the GIF block structure without decompressing any frame
+func countGIFFrames(buffer []byte) (int, error) {
+	truncated := fmt.Errorf("failed to count gif frames: truncated file")
+
+	// Header (6) + logical screen descriptor (7)
+	if len(buffer) < 13 {
+		return 0, truncated
+	}
+	pos := 13
+	if packed := buffer[10]; packed&0x80 != 0 {
+		pos += 3 * (1 << ((packed & 0x07) + 1))
+	}
+
+	skipSubBlocks := func() error {
+		for {
+			if pos >= len(buffer) {
+				return truncated
+			}
+			size := int(buffer[pos])
+			pos++
+			if size == 0 {
+				return nil
+			}
+			pos += size
+		}
+	}
+
+	frames := 0
+	for {
+		if pos >= len(buffer) {
+			return 0, truncated
+		}
+		switch buffer[pos] {
+		case 0x21: // Extension: introducer, label, data sub-blocks
+			pos += 2
+			if err := skipSubBlocks(); err != nil {
+				return 0, err
+			}
+		case 0x2C: // Image descriptor: 10 bytes, optional local color table, LZW code size, data sub-blocks
+			if pos+10 > len(buffer) {
+				return 0, truncated
+			}
+			packed := buffer[pos+9]
+			pos += 10
+			if packed&0x80 != 0 {
+				pos += 3 * (1 << ((packed & 0x07) + 1))
+			}
+			pos++
+			if err := skipSubBlocks(); err != nil {
+				return 0, err
+			}
+			frames++
+		case 0x3B: // Trailer
+			return frames, nil
+		default:
+			return 0, fmt.Errorf("failed to count gif frames: unknown block 0x%02x", buffer[pos])
+		}
+	}
+}
@@ -0,0 +1,190 @@
+package validation
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/gif"
+	"image/png"
+	"testing"
+)
+
+func encodePNG(t *testing.T, width int, height int) []byte {
+	t.Helper()
+	buf := new(bytes.Buffer)
+	if err := png.Encode(buf, image.NewNRGBA(image.Rect(0, 0, width, height))); err != nil {
+		t.Fatalf("encoding png: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// encodeGIF encodes an animated GIF, local color tables exercise the optional table of each descriptor
+func encodeGIF(t *testing.T, frames int, localPalette bool) []byte {
+	t.Helper()
+	animation := &gif.GIF{}
+	for i := 0; i < frames; i++ {
+		frame := image.NewPaletted(image.Rect(0, 0, 4, 4), palette.Plan9)
+		if localPalette && i%2 == 1 {
+			frame = image.NewPaletted(image.Rect(0, 0, 4, 4), color.Palette{color.Black, color.White})
+		}
+		frame.SetColorIndex(i%4, i%4, 1)
+		animation.Image = append(animation.Image, frame)
+		animation.Delay = append(animation.Delay, 10)
+	}
+	buf := new(bytes.Buffer)
+	if err := gif.EncodeAll(buf, animation); err != nil {
+		t.Fatalf("encoding gif: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestCheckSize(t *testing.T) {
+	tests := []struct {
+		maxBytes int64
+		size     int64
+		tooLarge bool
+	}{
+		{maxBytes: 0, size: 1 << 40},
+		{maxBytes: 1024, size: 1024},
+		{maxBytes: 1024, size: 1025, tooLarge: true},
+	}
+	for _, tt := range tests {
+		err := Limits{MaxBytes: tt.maxBytes}.CheckSize(tt.size)
+		if got := errors.Is(err, ErrImageTooLarge); got != tt.tooLarge {
+			t.Errorf("CheckSize(%d) with max %d = %v, want too large %t", tt.size, tt.maxBytes, err, tt.tooLarge)
+		}
+	}
+}
+
+func TestCheckDimensions(t *testing.T) {
+	tests := []struct {
+		maxPixels     int64
+		width, height int
+		tooLarge      bool
+		invalid       bool
+	}{
+		{maxPixels: 0, width: 100000, height: 100000},
+		{maxPixels: 10000, width: 100, height: 100},
+		{maxPixels: 10000, width: 100, height: 101, tooLarge: true},
+		// int overflow on 32 bit platforms must not let a huge canvas through
+		{maxPixels: 10000, width: 1 << 20, height: 1 << 20, tooLarge: true},
+		{maxPixels: 10000, width: -1, height: 10, invalid: true},
+	}
+	for _, tt := range tests {
+		err := Limits{MaxPixels: tt.maxPixels}.CheckDimensions(tt.width, tt.height)
+		switch {
+		case tt.tooLarge && !errors.Is(err, ErrImageTooLarge):
+			t.Errorf("CheckDimensions(%dx%d) = %v, want ErrImageTooLarge", tt.width, tt.height, err)
+		case tt.invalid && (err == nil || errors.Is(err, ErrImageTooLarge)):
+			t.Errorf("CheckDimensions(%dx%d) = %v, want invalid dimensions", tt.width, tt.height, err)
+		case !tt.tooLarge && !tt.invalid && err != nil:
+			t.Errorf("CheckDimensions(%dx%d) = %v, want nil", tt.width, tt.height, err)
+		}
+	}
+}
+
+func TestCheckImage(t *testing.T) {
+	smallPNG := encodePNG(t, 20, 10)
+	threeFrames := encodeGIF(t, 3, false)
+	tests := []struct {
+		name     string
+		buffer   []byte
+		format   string
+		limits   Limits
+		tooLarge bool
+	}{
+		{name: "within every limit", buffer: smallPNG, format: FormatPNG, limits: Limits{MaxPixels: 200, MaxBytes: 1 << 20, MaxFrames: 1}},
+		{name: "no limits", buffer: smallPNG, format: FormatPNG},
+		{name: "over pixels", buffer: smallPNG, format: FormatPNG, limits: Limits{MaxPixels: 199}, tooLarge: true},
+		{name: "over bytes", buffer: smallPNG, format: FormatPNG, limits: Limits{MaxBytes: 10}, tooLarge: true},
+		{name: "frames at the limit", buffer: threeFrames, format: FormatGIF, limits: Limits{MaxFrames: 3}},
+		{name: "over frames", buffer: threeFrames, format: FormatGIF, limits: Limits{MaxFrames: 2}, tooLarge: true},
+		{name: "frames only checked for gif", buffer: smallPNG, format: FormatPNG, limits: Limits{MaxFrames: 0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := tt.limits.CheckImage(tt.buffer, tt.format)
+			if tt.tooLarge {
+				if !errors.Is(err, ErrImageTooLarge) {
+					t.Fatalf("CheckImage() = %v, want ErrImageTooLarge", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("CheckImage() = %v", err)
+			}
+			if cfg.Width == 0 || cfg.Height == 0 {
+				t.Fatalf("CheckImage() returned an empty config %+v", cfg)
+			}
+		})
+	}
+}
+
+func TestCheckImageHugeCanvasSmallFile(t *testing.T) {
+	// A valid PNG header declaring 100000x100000 with no pixel data: rejected from the header alone
+	header := encodePNG(t, 1, 1)[:33]
+	header[16], header[17], header[18], header[19] = 0x00, 0x01, 0x86, 0xA0
+	header[20], header[21], header[22], header[23] = 0x00, 0x01, 0x86, 0xA0
+	binary.BigEndian.PutUint32(header[29:33], crc32.ChecksumIEEE(header[12:29]))
+	_, err := Limits{MaxPixels: 50000000}.CheckImage(header, FormatPNG)
+	if !errors.Is(err, ErrImageTooLarge) {
+		t.Fatalf("CheckImage() = %v, want ErrImageTooLarge", err)
+	}
+}
+
+func TestCountGIFFrames(t *testing.T) {
+	tests := []struct {
+		name   string
+		buffer []byte
+		frames int
+	}{
+		{name: "single frame", buffer: encodeGIF(t, 1, false), frames: 1},
+		{name: "animated", buffer: encodeGIF(t, 7, false), frames: 7},
+		{name: "local color tables", buffer: encodeGIF(t, 5, true), frames: 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			frames, err := countGIFFrames(tt.buffer)
+			if err != nil || frames != tt.frames {
+				t.Fatalf("countGIFFrames() = %d, %v, want %d", frames, err, tt.frames)
+			}
+		})
+	}
+}
+
+func TestCountGIFFramesMalformed(t *testing.T) {
+	valid := encodeGIF(t, 3, true)
+	unknownBlock := append([]byte{}, valid...)
+	unknownBlock[len(unknownBlock)-1] = 0x42
+
+	tests := []struct {
+		name   string
+		buffer []byte
+	}{
+		{name: "empty", buffer: nil},
+		{name: "header only", buffer: valid[:6]},
+		{name: "inside the screen descriptor", buffer: valid[:12]},
+		{name: "inside the global color table", buffer: valid[:20]},
+		{name: "missing trailer", buffer: valid[:len(valid)-1]},
+		{name: "inside the last frame", buffer: valid[:len(valid)-5]},
+		{name: "unknown block", buffer: unknownBlock},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if frames, err := countGIFFrames(tt.buffer); err == nil {
+				t.Fatalf("countGIFFrames() = %d, nil, want an error", frames)
+			}
+		})
+	}
+
+	// Every truncation of a valid file must fail cleanly, never panic or count frames it did not see
+	for end := 0; end < len(valid); end++ {
+		if _, err := countGIFFrames(valid[:end]); err == nil {
+			t.Fatalf("countGIFFrames() of the first %d of %d bytes did not fail", end, len(valid))
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package validation
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+)
+
+// ErrUnsupportedFile is returned when the raw file is not an allowed image or its content does not match its extension
+var ErrUnsupportedFile = errors.New("unsupported or disguised file")
+
+const (
+	FormatJPEG = "jpeg"
+	FormatPNG  = "png"
+	FormatGIF  = "gif"
+	FormatBMP  = "bmp"
+	FormatTIFF = "tiff"
+	FormatWEBP = "webp"
+)
+
+// AllowedFormats are the formats the transformation package can decode
+var AllowedFormats = map[string]bool{
+	FormatJPEG: true,
+	FormatPNG:  true,
+	FormatGIF:  true,
+	FormatBMP:  true,
+	FormatTIFF: true,
+}
+
+var extensionFormats = map[string]string{
+	".jpg":  FormatJPEG,
+	".jpeg": FormatJPEG,
+	".png":  FormatPNG,
+	".gif":  FormatGIF,
+	".bmp":  FormatBMP,
+	".tif":  FormatTIFF,
+	".tiff": FormatTIFF,
+	".webp": FormatWEBP,
+}
+
+type signature struct {
+	format string
+	offset int
+	magic  []byte
+}
+
+var signatures = []signature{
+	{FormatJPEG, 0, []byte{0xFF, 0xD8, 0xFF}},
+	{FormatPNG, 0, []byte("\x89PNG\r\n\x1a\n")},
+	{FormatGIF, 0, []byte("GIF87a")},
+	{FormatGIF, 0, []byte("GIF89a")},
+	{FormatBMP, 0, []byte("BM")},
+	{FormatTIFF, 0, []byte("II*\x00")},
+	{FormatTIFF, 0, []byte("MM\x00*")},
+	// WebP is RIFF....WEBP, the size in between is skipped
+	{FormatWEBP, 8, []byte("WEBP")},
+}
+
+// Sniff detects the image format from the leading magic bytes, it returns "" when nothing matches
+func Sniff(buffer []byte) string {
+	for _, sig := range signatures {
+		end := sig.offset + len(sig.magic)
+		if len(buffer) < end {
+			continue
+		}
+		if bytes.Equal(buffer[sig.offset:end], sig.magic) {
+			if sig.format == FormatWEBP && !bytes.HasPrefix(buffer, []byte("RIFF")) {
+				continue
+			}
+			return sig.format
+		}
+	}
+	return ""
+}
+
+// ValidateImage checks that buffer holds an allowed image format and that the extension of key agrees
+// with the real content, it returns the sniffed format.
+func ValidateImage(buffer []byte, key string) (string, error) {
+	format := Sniff(buffer)
+	if format == "" {
+		return "", fmt.Errorf("%w: unknown file signature for %s", ErrUnsupportedFile, key)
+	}
+	if !AllowedFormats[format] {
+		return "", fmt.Errorf("%w: %s files are not allowed (%s)", ErrUnsupportedFile, format, key)
+	}
+
+	ext := strings.ToLower(path.Ext(key))
+	expected, ok := extensionFormats[ext]
+	if !ok {
+		return "", fmt.Errorf("%w: unknown extension %q for %s", ErrUnsupportedFile, ext, key)
+	}
+	if expected != format {
+		return "", fmt.Errorf("%w: %s declares %s but contains %s", ErrUnsupportedFile, key, expected, format)
+	}
+	return format, nil
+}
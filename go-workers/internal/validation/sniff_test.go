@@ -0,0 +1,72 @@
+package validation
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSniff(t *testing.T) {
+	tests := []struct {
+		name   string
+		buffer []byte
+		want   string
+	}{
+		{name: "jpeg", buffer: []byte{0xFF, 0xD8, 0xFF, 0xE0, 0x00}, want: FormatJPEG},
+		{name: "png", buffer: []byte("\x89PNG\r\n\x1a\n\x00\x00"), want: FormatPNG},
+		{name: "gif87a", buffer: []byte("GIF87a\x01\x00"), want: FormatGIF},
+		{name: "gif89a", buffer: []byte("GIF89a\x01\x00"), want: FormatGIF},
+		{name: "bmp", buffer: []byte("BM\x36\x00"), want: FormatBMP},
+		{name: "tiff little endian", buffer: []byte("II*\x00\x08\x00"), want: FormatTIFF},
+		{name: "tiff big endian", buffer: []byte("MM\x00*\x00\x08"), want: FormatTIFF},
+		{name: "webp", buffer: []byte("RIFF\x24\x00\x00\x00WEBPVP8 "), want: FormatWEBP},
+		{name: "webp marker without riff", buffer: []byte("RIFX\x24\x00\x00\x00WEBP"), want: ""},
+		{name: "gif version unknown", buffer: []byte("GIF88a"), want: ""},
+		{name: "truncated png", buffer: []byte("\x89PN"), want: ""},
+		{name: "pdf", buffer: []byte("%PDF-1.7"), want: ""},
+		{name: "empty", buffer: nil, want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Sniff(tt.buffer); got != tt.want {
+				t.Fatalf("Sniff() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateImage(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\n")
+	jpeg := []byte{0xFF, 0xD8, 0xFF, 0xE0}
+	tests := []struct {
+		name   string
+		buffer []byte
+		key    string
+		want   string
+		err    bool
+	}{
+		{name: "png", buffer: png, key: "raw/cat.png", want: FormatPNG},
+		{name: "upper case extension", buffer: png, key: "raw/cat.PNG", want: FormatPNG},
+		{name: "jpg extension", buffer: jpeg, key: "raw/cat.jpg", want: FormatJPEG},
+		{name: "jpeg extension", buffer: jpeg, key: "raw/cat.jpeg", want: FormatJPEG},
+		{name: "tif extension", buffer: []byte("II*\x00"), key: "raw/scan.tif", want: FormatTIFF},
+		{name: "disguised as png", buffer: jpeg, key: "raw/cat.png", err: true},
+		{name: "script renamed", buffer: []byte("#!/bin/sh\n"), key: "raw/cat.png", err: true},
+		{name: "webp not decodable", buffer: []byte("RIFF\x24\x00\x00\x00WEBP"), key: "raw/cat.webp", err: true},
+		{name: "unknown extension", buffer: png, key: "raw/cat.svg", err: true},
+		{name: "no extension", buffer: png, key: "raw/cat", err: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ValidateImage(tt.buffer, tt.key)
+			if tt.err {
+				if !errors.Is(err, ErrUnsupportedFile) {
+					t.Fatalf("ValidateImage() = %v, want ErrUnsupportedFile", err)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Fatalf("ValidateImage() = %q, %v, want %q", got, err, tt.want)
+			}
+		})
+	}
+}
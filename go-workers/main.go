@@ -5,10 +5,15 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"syscall"
 
 	"github.com/mahirjain10/go-workers/config"
+	"github.com/mahirjain10/go-workers/internal/admin"
 	"github.com/mahirjain10/go-workers/internal/aws"
+	"github.com/mahirjain10/go-workers/internal/idempotency"
+	"github.com/mahirjain10/go-workers/internal/outbox"
 	"github.com/mahirjain10/go-workers/internal/queue"
 	"github.com/mahirjain10/go-workers/internal/utils"
 
@@ -16,10 +21,12 @@ import (
 )
 
 type App struct {
-	config          *config.Config
-	rabbitMqConn    *amqp.Connection
-	s3Service       *aws.S3Service
-	rabbitMqService *queue.RabbitMqService
+	config           *config.Config
+	rabbitMqConn     *amqp.Connection
+	s3Service        *aws.S3Service
+	rabbitMqService  *queue.RabbitMqService
+	idempotencyStore idempotency.Store
+	statusOutbox     *outbox.Outbox
 }
 
 // NewApp creates and initializes a new App instance with all dependencies
@@ -47,7 +54,7 @@ func NewApp(ctx context.Context) (*App, error) {
 	downloadPath := filepath.Join(dir, "images")
 	uploadPath := filepath.Join(dir, "images")
 
-	s3Service := aws.NewS3Service(s3Client, envConfig.AwsBucketName, downloadPath, uploadPath)
+	s3Service := aws.NewS3Service(s3Client, envConfig.AwsBucketName, downloadPath, uploadPath, envConfig.S3RetryPolicy)
 
 	// Connect to RabbitMQ
 	conn, err := utils.NewRabbitMQClient(envConfig.RabbitMqURL)
@@ -55,21 +62,37 @@ func NewApp(ctx context.Context) (*App, error) {
 		return nil, fmt.Errorf("failed to connect to RabbitMQ: %w", err)
 	}
 
+	// Completed jobs are remembered so redelivered messages are not processed twice
+	idempotencyStore, err := idempotency.NewStore(envConfig.Idempotency)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize idempotency store: %w", err)
+	}
+
+	// Status messages wait in the outbox until the broker confirms them
+	statusOutbox, err := outbox.Open(envConfig.OutboxPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open status outbox: %w", err)
+	}
+
 	// Initialize RabbitMQ service (no need to create channel here)
-	rabbitMqService := queue.NewRabbitMqService(s3Service, conn, envConfig)
+	rabbitMqService := queue.NewRabbitMqService(s3Service, conn, envConfig, idempotencyStore, statusOutbox)
 	log.Printf("rabbit mq service init : %v", rabbitMqService)
 
 	// Return the fully initialized App
 	return &App{
-		config:       envConfig,
-		rabbitMqConn: conn,
-		s3Service:       s3Service,
-		rabbitMqService: rabbitMqService,
+		config:           envConfig,
+		rabbitMqConn:     conn,
+		s3Service:        s3Service,
+		rabbitMqService:  rabbitMqService,
+		idempotencyStore: idempotencyStore,
+		statusOutbox:     statusOutbox,
 	}, nil
 }
 
 func main() {
-	ctx := context.Background()
+	// SIGTERM (docker stop) and SIGINT cancel ctx, which stops consuming and drains in-flight jobs
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
 	// Initialize application
 	app, err := NewApp(ctx)
@@ -77,12 +100,42 @@ func main() {
 		log.Fatalf("Failed to initialize application: %v", err)
 	}
 	log.Printf("loging app: %v", app)
+	defer app.idempotencyStore.Close()
+	defer app.statusOutbox.Close()
 
 
 	log.Println("Application initialized successfully")
 
+	// SIGHUP re-reads the worker counts and scales the consumers to them
+	go reloadWorkersOnSighup(ctx, app.rabbitMqService)
+
+	if app.config.AdminAddr != "" {
+		adminServer := admin.NewServer(app.config.AdminAddr, app.rabbitMqService)
+		adminServer.Start()
+		defer adminServer.Shutdown(context.Background())
+	}
+
 	// Start consuming messages
-	if err := app.rabbitMqService.Start(app.rabbitMqConn, ctx); err != nil {
+	if err := app.rabbitMqService.Start(ctx); err != nil {
 		log.Fatalf("Failed to start application: %v", err)
 	}
+	log.Println("Application stopped")
+}
+
+func reloadWorkersOnSighup(ctx context.Context, rabbitMqService *queue.RabbitMqService) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			log.Println("SIGHUP received, reloading worker counts")
+			if err := rabbitMqService.ReloadWorkers(); err != nil {
+				log.Printf("Failed to reload worker counts: %v", err)
+			}
+		}
+	}
 }
@@ -43,24 +43,28 @@ import { EventEmitterModule } from '@nestjs/event-emitter';
         queue: 'rotate_queue',
         durable: true,
         prefetchCount: 5,
+        priority: true,
       },
       {
         name: 'RESIZE_QUEUE',
         queue: 'resize_queue',
         durable: true,
         prefetchCount: 5,
+        priority: true,
       },
       {
         name: 'FORCE_RESIZE_QUEUE',
         queue: 'force_resize_queue',
         durable: true,
         prefetchCount: 5,
+        priority: true,
       },
       {
         name: 'CONVERT_QUEUE',
         queue: 'convert_queue',
         durable: true,
         prefetchCount: 5,
+        priority: true,
       },
       // {
       //   name: 'STATUS_QUEUE',
@@ -17,7 +17,10 @@ export default () => ({
   },
   rabbitmq:{
     url:process.env.RABBITMQ_URL,
-    queue:process.env.RABBITMQ_QUEUE
+    queue:process.env.RABBITMQ_QUEUE,
+    // must match QUEUE_MAX_PRIORITY of the go workers, 0 disables priority queues. Existing queues
+    // have to be deleted before changing it, RabbitMQ will not redeclare them with another value
+    maxPriority:parseInt(process.env.RABBITMQ_MAX_PRIORITY ?? '0', 10)
   },
   redis:{
     url:process.env.REDIS_URL,
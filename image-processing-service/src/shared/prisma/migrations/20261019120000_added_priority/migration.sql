@@ -0,0 +1,2 @@
+-- AlterTable
+ALTER TABLE "image_processing" ADD COLUMN     "priority" INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,3 @@
+-- AlterEnum
+ALTER TYPE "STATUS" ADD VALUE 'CANCELLED';
+ALTER TYPE "STATUS" ADD VALUE 'EXPIRED';
@@ -7,6 +7,8 @@ interface QueueConfig {
   queue: string;
   durable?: boolean;
   prefetchCount?: number;
+  // work queues are priority queues, declared with the same x-max-priority as the go workers
+  priority?: boolean;
 }
 
 @Global()
@@ -14,7 +16,7 @@ interface QueueConfig {
 export class RabbitmqModule {
   static register(queues: QueueConfig[]): DynamicModule {
     const clients = queues.map(
-      ({ name, queue, durable = true, prefetchCount }) => ({
+      ({ name, queue, durable = true, prefetchCount, priority = false }) => ({
         name,
         imports: [ConfigModule],
         useFactory: (configService: ConfigService): RmqOptions => {
@@ -27,6 +29,8 @@ export class RabbitmqModule {
             );
           }
 
+          const maxPriority = configService.get<number>('rabbitmq.maxPriority') ?? 0;
+
           return {
             transport: Transport.RMQ,
             options: {
@@ -34,7 +38,13 @@ export class RabbitmqModule {
               exchange: 'image_processing',
               urls: [rmqUrl],
               queue,
-              queueOptions: { durable },
+              queueOptions: {
+                durable,
+                ...(priority &&
+                  maxPriority > 0 && {
+                    arguments: { 'x-max-priority': maxPriority },
+                  }),
+              },
               ...(prefetchCount && { prefetchCount }),
             },
           };
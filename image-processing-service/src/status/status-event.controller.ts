@@ -10,6 +10,15 @@ import {
 import { EventEmitter2 } from '@nestjs/event-emitter';
 import { StatusData } from './interface/status.interface';
 
+// Statuses the Go worker publishes, anything else is stored as FAILED
+const WORKER_STATUSES: STATUS[] = [
+  STATUS.PROCESSING,
+  STATUS.PROCESSED,
+  STATUS.FAILED,
+  STATUS.CANCELLED,
+  STATUS.EXPIRED,
+];
+
 @Controller()
 export class StatusEventController {
   private readonly logger = new Logger(StatusEventController.name);
@@ -59,11 +68,7 @@ export class StatusEventController {
     );
 
     const status =
-      statusRaw === STATUS.PROCESSING
-        ? STATUS.PROCESSING
-        : statusRaw === STATUS.PROCESSED
-          ? STATUS.PROCESSED
-          : STATUS.FAILED;
+      WORKER_STATUSES.find((known) => known === statusRaw) ?? STATUS.FAILED;
 
     const publicUrl =
       publicUrlRaw === undefined || publicUrlRaw === '' ? null : publicUrlRaw;
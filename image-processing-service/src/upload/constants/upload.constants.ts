@@ -4,11 +4,22 @@ export enum STATUS {
     UPLOADING='UPLOADING',
     PROCESSING = 'PROCESSING',
     PROCESSED = 'PROCESSED',
-    FAILED = 'FAILED'
+    FAILED = 'FAILED',
+    CANCELLED = 'CANCELLED',
+    EXPIRED = 'EXPIRED'
 }
 export enum TRANSFORMATION_TYPE {
   ROTATE="ROTATE",
   RESIZE="RESIZE",
   FORCE_RESIZE="FORCE_RESIZE",
   CONVERT="CONVERT"
-}
\ No newline at end of file
+}
+export enum PLAN {
+  FREE="FREE",
+  PRO="PRO"
+}
+// Job priority per plan, the webhook caps it at RABBITMQ_MAX_PRIORITY
+export const PLAN_PRIORITY: Record<PLAN, number> = {
+  [PLAN.FREE]: 0,
+  [PLAN.PRO]: 5
+}
@@ -6,6 +6,8 @@ import { PutObjectCommand, S3Client } from '@aws-sdk/client-s3';
 
 import {
   EXPIRES_IN,
+  PLAN,
+  PLAN_PRIORITY,
   STATUS,
   TRANSFORMATION_TYPE,
 } from './constants/upload.constants';
@@ -80,6 +82,15 @@ export class UploadService {
     };
   }
 
+  // The job priority is an entitlement of the user's plan, clients cannot ask for one
+  private async priorityFor(userID: string): Promise<number> {
+    const user = await this.prismaService.user.findUnique({
+      where: { id: userID },
+      select: { plan: true },
+    });
+    return PLAN_PRIORITY[(user?.plan as PLAN) ?? PLAN.FREE] ?? 0;
+  }
+
   public async generatePresignedUrl(
     userID: string,
     filename: string,
@@ -93,6 +104,7 @@ export class UploadService {
     },
   ) {
     // 1. Create image record before upload
+    const priority = await this.priorityFor(userID);
     const imageRecord = await this.imageProcessingDb.create({
       data: {
         userId: userID,
@@ -100,6 +112,7 @@ export class UploadService {
         status: STATUS.PENDING,
         transformationType,
         transformationParameters: JSON.stringify(transformationParamters),
+        priority,
       },
     });
     this.logger.debug('image record while saving: ', imageRecord);
@@ -1,6 +1,11 @@
 import { HttpService } from '@nestjs/axios';
 import { Inject, Injectable, Logger } from '@nestjs/common';
-import { ClientProxy } from '@nestjs/microservices';
+import {
+  ClientProxy,
+  RmqRecord,
+  RmqRecordBuilder,
+} from '@nestjs/microservices';
+import { ConfigService } from '@nestjs/config';
 import { ImageProcessing } from '@shared/prisma/generated/client';
 import { PrismaService } from '@shared/prisma/prisma.service';
 import { firstValueFrom, take, timeout } from 'rxjs';
@@ -14,6 +19,7 @@ export class WebhookService {
   constructor(
     private readonly httpService: HttpService,
     private readonly prismaService: PrismaService,
+    private readonly configService: ConfigService,
     @Inject('ROTATE_QUEUE') private readonly RotateQueue: ClientProxy,
     @Inject('RESIZE_QUEUE') private readonly ResizeQueue: ClientProxy,
     @Inject('FORCE_RESIZE_QUEUE')
@@ -28,6 +34,23 @@ export class WebhookService {
     });
     return data;
   };
+  // toRecord sets the AMQP priority of the job, capped at the x-max-priority
+  // of the work queues. The message body stays the row itself.
+  private toRecord = (
+    imageProcessing: ImageProcessing | null,
+  ): RmqRecord<ImageProcessing | null> => {
+    const maxPriority =
+      this.configService.get<number>('rabbitmq.maxPriority') ?? 0;
+    const builder = new RmqRecordBuilder(imageProcessing);
+    if (maxPriority > 0) {
+      const priority = Math.min(
+        Math.max(imageProcessing?.priority ?? 0, 0),
+        maxPriority,
+      );
+      builder.setOptions({ priority });
+    }
+    return builder.build();
+  };
   snsHandshake = async (subscribeUrl: string) => {
     this.logger.log('Received SNS Subscription Confirmation. Confirming...');
     try {
@@ -63,7 +86,7 @@ export class WebhookService {
     let value;
     switch (transformationType) {
       case TRANSFORMATION_TYPE.CONVERT:
-        this.ConvertQueue.emit('convert_queue', imageProcessing)
+        this.ConvertQueue.emit('convert_queue', this.toRecord(imageProcessing))
           .pipe(take(1), timeout(5000))
           .subscribe({
             next: () =>
@@ -79,7 +102,8 @@ export class WebhookService {
         this.logger.debug('Pushed into convert_queue');
         break;
       case TRANSFORMATION_TYPE.FORCE_RESIZE:
-        this.ForceResizeQueue.emit('force_resize_queue', imageProcessing)
+        this.ForceResizeQueue
+          .emit('force_resize_queue', this.toRecord(imageProcessing))
           .pipe(take(1), timeout(5000))
           .subscribe({
             next: () =>
@@ -95,7 +119,7 @@ export class WebhookService {
         this.logger.debug('Pushed into force_resize_queue');
         break;
       case TRANSFORMATION_TYPE.RESIZE:
-        this.ResizeQueue.emit('resize_queue', imageProcessing)
+        this.ResizeQueue.emit('resize_queue', this.toRecord(imageProcessing))
           .pipe(take(1), timeout(5000))
           .subscribe({
             next: () =>
@@ -111,7 +135,7 @@ export class WebhookService {
         this.logger.debug('Pushed into resize_queue');
         break;
       case TRANSFORMATION_TYPE.ROTATE:
-        this.RotateQueue.emit('rotate_queue', imageProcessing)
+        this.RotateQueue.emit('rotate_queue', this.toRecord(imageProcessing))
           .pipe(take(1), timeout(5000))
           .subscribe({
             next: () =>